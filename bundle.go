@@ -0,0 +1,257 @@
+package grok
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+)
+
+// BundleVersion is the version of the bundle format written by this
+// package. Bundles of other versions cannot be loaded.
+const BundleVersion = 1
+
+// bundleMagic starts every bundle in the binary format
+const bundleMagic = "GROKBNDL"
+
+// ErrStaleBundle is returned by NewFromBundle if a bundle was built from
+// other pattern definitions or options than the ones passed to it.
+var ErrStaleBundle = errors.New("bundle was built from different patterns")
+
+// Bundle is a serializable snapshot of resolved patterns. Loading a bundle
+// with NewFromBundle skips resolving the pattern references, which makes
+// creating a Grok instance with many patterns considerably faster.
+// Use Grok.Bundle to create a bundle.
+type Bundle struct {
+	Version int `json:"version"`
+	// Checksum identifies the pattern definitions and options the bundle
+	// was built from
+	Checksum          string                    `json:"checksum"`
+	NamedCapturesOnly bool                      `json:"namedCapturesOnly"`
+	Patterns          map[string]BundledPattern `json:"patterns"`
+}
+
+// BundledPattern is a resolved pattern stored in a Bundle.
+type BundledPattern struct {
+	// Definition is the pattern as it was passed to New
+	Definition string `json:"definition"`
+	// Origin is the expanded expression with the original capture names
+	Origin string `json:"origin"`
+	// Expression is the expanded expression with generated capture names
+	Expression string `json:"expression"`
+	// TypeHints holds the type of each typed capture by name
+	TypeHints map[string]string `json:"typeHints,omitempty"`
+	// Aliases maps generated capture names to the original names
+	Aliases map[string]string `json:"aliases,omitempty"`
+}
+
+// Bundle returns a snapshot of all patterns known to this instance.
+func (grok Grok) Bundle() *Bundle {
+	bundle := &Bundle{
+		Version:           BundleVersion,
+		NamedCapturesOnly: grok.namedOnly,
+		Patterns:          make(map[string]BundledPattern, len(grok.patterns)),
+	}
+	for name, pattern := range grok.patterns {
+		bundle.Patterns[name] = BundledPattern{
+			Definition: pattern.definition,
+			Origin:     pattern.origin,
+			Expression: pattern.expression,
+			TypeHints:  pattern.typeHints,
+			Aliases:    pattern.aliasMap,
+		}
+	}
+	bundle.Checksum = bundle.definitionChecksum()
+	return bundle
+}
+
+// WriteJSON writes the bundle as JSON.
+func (bundle *Bundle) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(bundle)
+}
+
+// WriteBinary writes the bundle in a compact binary format.
+func (bundle *Bundle) WriteBinary(w io.Writer) error {
+	if _, err := io.WriteString(w, bundleMagic); err != nil {
+		return err
+	}
+	return gob.NewEncoder(w).Encode(bundle)
+}
+
+// ReadBundle reads a bundle written by WriteJSON or WriteBinary.
+func ReadBundle(r io.Reader) (*Bundle, error) {
+	reader := bufio.NewReader(r)
+	bundle := &Bundle{}
+
+	magic, _ := reader.Peek(len(bundleMagic))
+	if bytes.Equal(magic, []byte(bundleMagic)) {
+		reader.Discard(len(bundleMagic))
+		if err := gob.NewDecoder(reader).Decode(bundle); err != nil {
+			return nil, fmt.Errorf("invalid bundle: %s", err)
+		}
+	} else if err := json.NewDecoder(reader).Decode(bundle); err != nil {
+		return nil, fmt.Errorf("invalid bundle: %s", err)
+	}
+
+	if bundle.Version != BundleVersion {
+		return nil, fmt.Errorf("bundle version %d is not supported, expected %d", bundle.Version, BundleVersion)
+	}
+	if bundle.Checksum != bundle.definitionChecksum() {
+		return nil, errors.New("invalid bundle: checksum does not match the bundled patterns")
+	}
+	return bundle, nil
+}
+
+// NewFromBundle returns a Grok object using the resolved patterns of a
+// bundle. The config must hold the same patterns and options the bundle was
+// built with, otherwise ErrStaleBundle is returned. Only the pattern
+// definitions are compared, which is a lot cheaper than resolving them.
+func NewFromBundle(bundle *Bundle, config Config) (*Grok, error) {
+	if bundle.Version != BundleVersion {
+		return nil, fmt.Errorf("bundle version %d is not supported, expected %d", bundle.Version, BundleVersion)
+	}
+	if bundle.Checksum != patternChecksum(configDefinitions(config), config.NamedCapturesOnly) {
+		return nil, ErrStaleBundle
+	}
+
+	patterns := make(patternMap, len(bundle.Patterns))
+	for name, bundled := range bundle.Patterns {
+		typeHints := typeHintByKey(bundled.TypeHints)
+		if typeHints == nil {
+			typeHints = typeHintByKey{}
+		}
+		aliases := bundled.Aliases
+		if aliases == nil {
+			aliases = map[string]string{}
+		}
+		patterns[name] = &grokPattern{
+			definition: bundled.Definition,
+			origin:     bundled.Origin,
+			expression: bundled.Expression,
+			typeHints:  typeHints,
+			aliasMap:   aliases,
+		}
+	}
+
+	engine := config.Engine
+	if engine == nil {
+		engine = DefaultEngine
+	}
+
+	return &Grok{
+		patterns:    patterns,
+		namedOnly:   config.NamedCapturesOnly,
+		removeEmpty: config.RemoveEmptyValues,
+		engine:      engine,
+		normalize:   config.NormalizeName,
+		stats:       config.CollectStats,
+	}, nil
+}
+
+// NewFromBundleFile loads the bundle stored at path with NewFromBundle. If
+// the file does not exist, cannot be read or is stale, the patterns are
+// resolved with New and a new bundle is written to path. The bundle is only
+// a cache: if it cannot be written, the Grok is returned anyway and the
+// bundle is rebuilt on the next call. Use WriteBundleFile to handle these
+// errors.
+func NewFromBundleFile(path string, config Config) (*Grok, error) {
+	if file, err := os.Open(path); err == nil {
+		bundle, err := ReadBundle(file)
+		file.Close()
+		if err == nil {
+			if grok, err := NewFromBundle(bundle, config); err == nil {
+				return grok, nil
+			}
+		}
+	}
+
+	grok, err := New(config)
+	if err != nil {
+		return nil, err
+	}
+	WriteBundleFile(path, grok.Bundle())
+	return grok, nil
+}
+
+// WriteBundleFile writes the bundle in the binary format to path. The bundle
+// is written to a temporary file that replaces path, so readers never see a
+// partially written bundle.
+func WriteBundleFile(path string, bundle *Bundle) error {
+	file, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+
+	writer := bufio.NewWriter(file)
+	err = bundle.WriteBinary(writer)
+	if err == nil {
+		err = writer.Flush()
+	}
+	if err == nil {
+		err = file.Chmod(0644)
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(file.Name(), path)
+}
+
+// definitionChecksum returns the checksum of the definitions of all bundled
+// patterns
+func (bundle *Bundle) definitionChecksum() string {
+	definitions := make(map[string]string, len(bundle.Patterns))
+	for name, pattern := range bundle.Patterns {
+		definitions[name] = pattern.Definition
+	}
+	return patternChecksum(definitions, bundle.NamedCapturesOnly)
+}
+
+// configDefinitions returns the definitions New would resolve for the given
+// config. Patterns already defined by the default patterns are not replaced.
+func configDefinitions(config Config) map[string]string {
+	defaults := config.defaultPatterns()
+	definitions := make(map[string]string, len(defaults)+len(config.Patterns))
+	if !config.SkipDefaultPatterns {
+		for name, definition := range defaults {
+			definitions[name] = definition
+		}
+	}
+	for name, definition := range config.Patterns {
+		if _, defined := definitions[name]; !defined {
+			definitions[name] = definition
+		}
+	}
+	return definitions
+}
+
+// patternChecksum returns a hash over the sorted pattern definitions and the
+// options that change how they are resolved
+func patternChecksum(definitions map[string]string, namedOnly bool) string {
+	names := make([]string, 0, len(definitions))
+	for name := range definitions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	hash := sha256.New()
+	io.WriteString(hash, strconv.Itoa(BundleVersion)+"\n"+strconv.FormatBool(namedOnly)+"\n")
+	for _, name := range names {
+		io.WriteString(hash, strconv.Quote(name)+" "+strconv.Quote(definitions[name])+"\n")
+	}
+	return "sha256:" + hex.EncodeToString(hash.Sum(nil))
+}
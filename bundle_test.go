@@ -0,0 +1,107 @@
+package grok
+
+import (
+	"bytes"
+	"github.com/trivago/tgo/ttesting"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBundle(t *testing.T) {
+	expect := ttesting.NewExpect(t)
+
+	config := Config{
+		NamedCapturesOnly: true,
+		Patterns: map[string]string{
+			"REQUEST": `%{IP:client} %{WORD:verb} %{NUMBER:bytes:int}`,
+		},
+	}
+	g, err := New(config)
+	expect.NoError(err)
+
+	for _, write := range []func(*Bundle, *bytes.Buffer) error{
+		func(bundle *Bundle, buffer *bytes.Buffer) error { return bundle.WriteJSON(buffer) },
+		func(bundle *Bundle, buffer *bytes.Buffer) error { return bundle.WriteBinary(buffer) },
+	} {
+		buffer := bytes.Buffer{}
+		expect.NoError(write(g.Bundle(), &buffer))
+
+		bundle, err := ReadBundle(&buffer)
+		expect.NoError(err)
+		loaded, err := NewFromBundle(bundle, config)
+		expect.NoError(err)
+
+		compiled, err := loaded.Compile("%{REQUEST}")
+		expect.NoError(err)
+		matched, values, err := compiled.MatchAgainstTyped("10.0.0.1 GET 512")
+		expect.NoError(err)
+		expect.True(matched)
+		expect.Equal(map[string]interface{}{"client": "10.0.0.1", "verb": "GET", "bytes": 512}, values)
+	}
+
+	// Changed definitions or options make the bundle stale
+	bundle := g.Bundle()
+	_, err = NewFromBundle(bundle, Config{NamedCapturesOnly: true, Patterns: map[string]string{"REQUEST": `%{IP:client}`}})
+	expect.Equal(ErrStaleBundle, err)
+	_, err = NewFromBundle(bundle, Config{Patterns: config.Patterns})
+	expect.Equal(ErrStaleBundle, err)
+
+	// Bundles with modified patterns or of other versions are rejected
+	bundle.Patterns["REQUEST"] = BundledPattern{Definition: `%{IP:client}`}
+	buffer := bytes.Buffer{}
+	expect.NoError(bundle.WriteJSON(&buffer))
+	_, err = ReadBundle(&buffer)
+	expect.NotNil(err)
+
+	_, err = ReadBundle(strings.NewReader(`{"version": 0, "patterns": {}}`))
+	expect.NotNil(err)
+}
+
+func TestNewFromBundleFile(t *testing.T) {
+	expect := ttesting.NewExpect(t)
+	path := filepath.Join(t.TempDir(), "patterns.bundle")
+
+	config := Config{NamedCapturesOnly: true, Patterns: map[string]string{"REQUEST": `%{IP:client} %{WORD:verb}`}}
+	for i := 0; i < 2; i++ {
+		g, err := NewFromBundleFile(path, config)
+		expect.NoError(err)
+		compiled, err := g.Compile("%{REQUEST}")
+		expect.NoError(err)
+		expect.Equal([]string{"client", "verb"}, compiled.FieldNames())
+	}
+
+	config.Patterns["REQUEST"] = `%{IP:server}`
+	g, err := NewFromBundleFile(path, config)
+	expect.NoError(err)
+	compiled, err := g.Compile("%{REQUEST}")
+	expect.NoError(err)
+	expect.Equal([]string{"server"}, compiled.FieldNames())
+
+	// No temporary files are left behind
+	files, err := os.ReadDir(filepath.Dir(path))
+	expect.NoError(err)
+	expect.Equal(1, len(files))
+
+	// The bundle is a cache, failing to write it does not fail
+	missing := filepath.Join(t.TempDir(), "missing", "patterns.bundle")
+	g, err = NewFromBundleFile(missing, config)
+	expect.NoError(err)
+	expect.NotNil(g)
+	expect.NotNil(WriteBundleFile(missing, g.Bundle()))
+}
+
+var resultBundle *Grok
+
+func BenchmarkNewFromBundle(b *testing.B) {
+	g, _ := New(Config{NamedCapturesOnly: true})
+	bundle := g.Bundle()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		g, _ = NewFromBundle(bundle, Config{NamedCapturesOnly: true})
+	}
+	resultBundle = g
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+)
+
+// runBundle writes the resolved patterns to a bundle file
+func runBundle(args []string) error {
+	flags := flag.NewFlagSet("bundle", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: grok bundle [flags]")
+		fmt.Fprintln(flags.Output(), "\nResolves the selected patterns and writes them as a bundle that can be loaded")
+		fmt.Fprintln(flags.Output(), "with grok.NewFromBundle using the same patterns and -named-only setting.")
+		flags.PrintDefaults()
+	}
+	patternFlags := addPatternFlags(flags)
+	output := flags.String("o", "-", "file to write the bundle to, \"-\" for stdout")
+	asJSON := flags.Bool("json", false, "write JSON instead of the binary format")
+	flags.Parse(args)
+
+	patternFlags.bundle = ""
+	g, err := patternFlags.newGrok()
+	if err != nil {
+		return err
+	}
+
+	out := os.Stdout
+	if *output != "-" {
+		if out, err = os.Create(*output); err != nil {
+			return err
+		}
+		defer out.Close()
+	}
+
+	writer := bufio.NewWriter(out)
+	bundle := g.Bundle()
+	if *asJSON {
+		err = bundle.WriteJSON(writer)
+	} else {
+		err = bundle.WriteBinary(writer)
+	}
+	if err != nil {
+		return err
+	}
+	return writer.Flush()
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/rtkjweeks/grok-go-pcre"
+)
+
+// runDebug explains why lines do or do not match an expression
+func runDebug(args []string) error {
+	flags := flag.NewFlagSet("debug", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: grok debug -p <expression> [flags] [line ...]")
+		fmt.Fprintln(flags.Output(), "\nShows the expanded expression and, for each line, where it stops matching.")
+		fmt.Fprintln(flags.Output(), "Lines are read from stdin if none are given.")
+		flags.PrintDefaults()
+	}
+	patternFlags := addPatternFlags(flags)
+	expression := flags.String("p", "", "grok expression to debug")
+	showTree := flags.Bool("tree", true, "print the tree of pattern references")
+	flags.Parse(args)
+
+	if len(*expression) == 0 {
+		flags.Usage()
+		return errors.New("no expression given, use -p")
+	}
+
+	g, err := patternFlags.newGrok()
+	if err != nil {
+		return err
+	}
+
+	lines := flags.Args()
+	if len(lines) == 0 {
+		scanner := bufio.NewScanner(os.Stdin)
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			lines = append(lines, scanner.Text())
+		}
+		if err := scanner.Err(); err != nil {
+			return err
+		}
+	}
+
+	out := bufio.NewWriter(os.Stdout)
+	defer out.Flush()
+
+	for i, line := range lines {
+		result, err := g.Debug(*expression, line)
+		if err != nil {
+			return err
+		}
+		if i == 0 {
+			fmt.Fprintf(out, "expression: %s\nexpanded:   %s\n", result.Expression, result.Expanded)
+			if *showTree {
+				fmt.Fprintln(out, "references:")
+				printDebugTree(out, result.Tree, 1)
+			}
+		}
+		printDebugResult(out, line, result)
+	}
+	return nil
+}
+
+// printDebugTree prints the reference tree with one reference per line
+func printDebugTree(out io.Writer, nodes []*grok.DebugNode, depth int) {
+	for _, node := range nodes {
+		fmt.Fprintf(out, "%s%s => %s\n", strings.Repeat("  ", depth), node.Reference, node.Definition)
+		printDebugTree(out, node.Children, depth+1)
+	}
+}
+
+// printDebugResult prints the match result of a single line
+func printDebugResult(out io.Writer, line string, result *grok.DebugResult) {
+	fmt.Fprintf(out, "\nline: %s\n", line)
+	if result.Matched {
+		fmt.Fprintln(out, "  matched")
+		return
+	}
+
+	failure := result.Failure
+	fmt.Fprintf(out, "%s^\n", strings.Repeat(" ", len("line: ")+failure.Offset))
+	fmt.Fprintf(out, "  no match, failed at offset %d\n", failure.Offset)
+	if len(failure.Path) > 0 {
+		fmt.Fprintf(out, "  within:         %s\n", strings.Join(failure.Path, " > "))
+	}
+	fmt.Fprintf(out, "  failing part:   %s\n", failure.Component)
+	fmt.Fprintf(out, "  matched prefix: %s\n", failure.MatchedPrefix)
+}
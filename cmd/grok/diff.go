@@ -0,0 +1,122 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+
+	"github.com/rtkjweeks/grok-go-pcre"
+	"github.com/rtkjweeks/grok-go-pcre/patterns"
+)
+
+// runDiff matches the selected patterns with two engines and reports where
+// the results differ
+func runDiff(args []string) error {
+	flags := flag.NewFlagSet("diff", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: grok diff [flags] [file ...]")
+		fmt.Fprintln(flags.Output(), "\nMatches every pattern of the selected sources, or the expression given by -p, with the")
+		fmt.Fprintln(flags.Output(), "engines given by -engine and -against and prints each input with different captures.")
+		fmt.Fprintln(flags.Output(), "Inputs are the lines of the given files, or the samples shipped with each pack, plus")
+		fmt.Fprintln(flags.Output(), "random variations of them.")
+		flags.PrintDefaults()
+	}
+	patternFlags := addPatternFlags(flags)
+	against := flags.String("against", "re2", "regexp engine to compare -engine with")
+	expression := flags.String("p", "", "grok expression to compare instead of all patterns")
+	random := flags.Int("n", 1000, "number of random inputs derived from the given inputs")
+	seed := flags.Int64("seed", 1, "seed for the random inputs")
+	flags.Parse(args)
+
+	first, err := grok.LookupEngine(patternFlags.engine)
+	if err != nil {
+		return err
+	}
+	second, err := grok.LookupEngine(*against)
+	if err != nil {
+		return err
+	}
+
+	lines := []string{}
+	for _, path := range flags.Args() {
+		if lines, err = readSamples(path, lines, math.MaxInt32); err != nil {
+			return err
+		}
+	}
+
+	failed := false
+	compare := func(source string, patterns map[string]string, expressions []string, inputs []string) error {
+		firstGrok, err := grok.New(grok.Config{Patterns: patterns, Engine: first})
+		if err != nil {
+			return fmt.Errorf("%s: %s", source, err)
+		}
+		secondGrok, err := grok.New(grok.Config{Patterns: patterns, Engine: second})
+		if err != nil {
+			return fmt.Errorf("%s: %s", source, err)
+		}
+
+		inputs = append(inputs, grok.GenerateInputs(inputs, *random, *seed)...)
+		for _, mismatch := range grok.CompareEngines(firstGrok, secondGrok, expressions, inputs) {
+			failed = true
+			fmt.Fprintf(os.Stdout, "%s: %s\n", source, mismatch)
+		}
+		return nil
+	}
+
+	if len(*expression) > 0 {
+		merged, err := patternFlags.patterns()
+		if err != nil {
+			return err
+		}
+		if err := compare("expression", merged, []string{*expression}, lines); err != nil {
+			return err
+		}
+	} else {
+		sources, err := patternFlags.sources()
+		if err != nil {
+			return err
+		}
+		for _, source := range sources {
+			inputs := lines
+			if len(flags.Args()) == 0 {
+				inputs, err = corpusInputs(source.name)
+				if err != nil {
+					return err
+				}
+			}
+
+			expressions := make([]string, 0, len(source.patterns))
+			for name := range source.patterns {
+				expressions = append(expressions, "%{"+name+"}")
+			}
+			sort.Strings(expressions)
+
+			if err := compare(source.name, source.patterns, expressions, inputs); err != nil {
+				return err
+			}
+		}
+	}
+
+	if failed {
+		return errors.New("engines differ")
+	}
+	return nil
+}
+
+// corpusInputs returns the sample lines shipped with a pack or nil if the
+// source is not a pack.
+func corpusInputs(pack string) ([]string, error) {
+	data, err := patterns.Corpus(pack)
+	if err != nil {
+		return nil, nil // not a pack
+	}
+	corpus, err := grok.ReadCorpus(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", pack, err)
+	}
+	return corpus.Inputs(), nil
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+)
+
+// runDiscover proposes an expression for a set of sample lines
+func runDiscover(args []string) error {
+	flags := flag.NewFlagSet("discover", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: grok discover [flags] [file ...]")
+		fmt.Fprintln(flags.Output(), "\nProposes a grok expression matching all sample lines of the given files or stdin.")
+		flags.PrintDefaults()
+	}
+	patternFlags := addPatternFlags(flags)
+	maxSamples := flags.Int("n", 100, "maximum number of sample lines to use")
+	flags.Parse(args)
+
+	g, err := patternFlags.newGrok()
+	if err != nil {
+		return err
+	}
+
+	paths := flags.Args()
+	if len(paths) == 0 {
+		paths = []string{"-"}
+	}
+
+	samples := []string{}
+	for _, path := range paths {
+		if samples, err = readSamples(path, samples, *maxSamples); err != nil {
+			return err
+		}
+	}
+
+	expression, err := g.Discover(samples)
+	if err != nil {
+		return err
+	}
+	fmt.Println(expression)
+	return nil
+}
+
+// readSamples appends the non-empty lines of a file, or stdin for "-", to
+// samples until max lines have been collected.
+func readSamples(path string, samples []string, max int) ([]string, error) {
+	input := os.Stdin
+	if path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			return samples, err
+		}
+		defer file.Close()
+		input = file
+	}
+
+	scanner := bufio.NewScanner(input)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for len(samples) < max && scanner.Scan() {
+		if len(scanner.Text()) > 0 {
+			samples = append(samples, scanner.Text())
+		}
+	}
+	return samples, scanner.Err()
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/rtkjweeks/grok-go-pcre"
+)
+
+// runLint checks the selected pattern packs and files for common mistakes
+func runLint(args []string) error {
+	flags := flag.NewFlagSet("lint", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: grok lint [flags]")
+		fmt.Fprintln(flags.Output(), "\nChecks the selected packs and pattern files. Each source is checked on its own.")
+		flags.PrintDefaults()
+	}
+	patternFlags := addPatternFlags(flags)
+	verbose := flags.Bool("v", false, "also report issues that are often intended, e.g. unreferenced patterns")
+	flags.Parse(args)
+
+	sources, err := patternFlags.sources()
+	if err != nil {
+		return err
+	}
+
+	failed := false
+	for _, source := range sources {
+		for _, issue := range grok.Lint(source.patterns) {
+			if issue.Severity == grok.LintInfo && !*verbose {
+				continue
+			}
+			failed = true
+			fmt.Fprintf(os.Stdout, "%s: %s\n", source.name, issue)
+		}
+	}
+
+	if failed {
+		return errors.New("issues found")
+	}
+	return nil
+}
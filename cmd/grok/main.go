@@ -0,0 +1,73 @@
+// Command grok parses log files with grok expressions.
+//
+// Usage:
+//
+//	grok -p '%{COMBINEDAPACHELOG}' [flags] [file ...]
+//	grok <command> [flags] [args ...]
+//
+// Without a command, the given files (or stdin) are parsed line by line and
+// matches are written to stdout. Run "grok -h" or "grok <command> -h" for
+// the available flags.
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+)
+
+// stdout receives the output of the parse and profile commands, replaced by
+// tests
+var stdout io.Writer = os.Stdout
+
+// command is a subcommand of the grok tool
+type command struct {
+	summary string
+	run     func(args []string) error
+}
+
+var commands = map[string]command{
+	"parse":    {"parse log lines and write the captured fields (default)", runParse},
+	"bundle":   {"write the resolved patterns to a bundle file", runBundle},
+	"debug":    {"show the expanded expression and where lines stop matching", runDebug},
+	"diff":     {"compare the captures of two regexp engines", runDiff},
+	"discover": {"propose an expression matching a set of sample lines", runDiscover},
+	"lint":     {"check pattern packs and files for common mistakes", runLint},
+	"profile":  {"report match times and the most expensive parts of expressions", runProfile},
+	"serve":    {"parse lines posted via HTTP", runServe},
+	"verify":   {"match sample corpora and compare the captured fields", runVerify},
+}
+
+func main() {
+	args := os.Args[1:]
+	name := "parse"
+	if len(args) > 0 {
+		if _, isCommand := commands[args[0]]; isCommand {
+			name, args = args[0], args[1:]
+		} else if args[0] == "help" {
+			usage()
+			return
+		}
+	}
+
+	if err := commands[name].run(args); err != nil {
+		fmt.Fprintf(os.Stderr, "grok %s: %s\n", name, err)
+		os.Exit(1)
+	}
+}
+
+// usage prints the list of available commands
+func usage() {
+	fmt.Fprintln(os.Stderr, "Usage: grok [command] [flags] [args ...]")
+	fmt.Fprintln(os.Stderr, "\nCommands:")
+
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "  %-10s %s\n", name, commands[name].summary)
+	}
+}
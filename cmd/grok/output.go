@@ -0,0 +1,132 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// recordWriter writes parsed records in a specific output format
+type recordWriter interface {
+	Write(record map[string]interface{}) error
+	Flush() error
+}
+
+// newRecordWriter returns a writer for the given format. Columns are used by
+// formats that need a fixed field order.
+func newRecordWriter(format string, out io.Writer, columns []string) (recordWriter, error) {
+	switch strings.ToLower(format) {
+	case "ndjson", "json":
+		return &ndjsonWriter{out: bufio.NewWriter(out)}, nil
+	case "csv":
+		return newDelimitedWriter(out, ',', columns), nil
+	case "tsv":
+		return newDelimitedWriter(out, '\t', columns), nil
+	case "logfmt":
+		return &logfmtWriter{out: bufio.NewWriter(out)}, nil
+	default:
+		return nil, fmt.Errorf("unknown output format %s, must be ndjson, csv, tsv or logfmt", format)
+	}
+}
+
+// ndjsonWriter writes one JSON object per line
+type ndjsonWriter struct {
+	out *bufio.Writer
+}
+
+func (w *ndjsonWriter) Write(record map[string]interface{}) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	w.out.Write(data)
+	return w.out.WriteByte('\n')
+}
+
+func (w *ndjsonWriter) Flush() error {
+	return w.out.Flush()
+}
+
+// delimitedWriter writes CSV or TSV with a header line
+type delimitedWriter struct {
+	out           *csv.Writer
+	columns       []string
+	headerWritten bool
+}
+
+func newDelimitedWriter(out io.Writer, delimiter rune, columns []string) *delimitedWriter {
+	writer := csv.NewWriter(out)
+	writer.Comma = delimiter
+	return &delimitedWriter{out: writer, columns: columns}
+}
+
+func (w *delimitedWriter) Write(record map[string]interface{}) error {
+	if !w.headerWritten {
+		w.headerWritten = true
+		if err := w.out.Write(w.columns); err != nil {
+			return err
+		}
+	}
+
+	row := make([]string, len(w.columns))
+	for i, column := range w.columns {
+		if value, isSet := record[column]; isSet {
+			row[i] = formatValue(value)
+		}
+	}
+	return w.out.Write(row)
+}
+
+func (w *delimitedWriter) Flush() error {
+	w.out.Flush()
+	return w.out.Error()
+}
+
+// logfmtWriter writes key=value pairs, sorted by key
+type logfmtWriter struct {
+	out *bufio.Writer
+}
+
+func (w *logfmtWriter) Write(record map[string]interface{}) error {
+	keys := make([]string, 0, len(record))
+	for key := range record {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for i, key := range keys {
+		if i > 0 {
+			w.out.WriteByte(' ')
+		}
+		w.out.WriteString(key)
+		w.out.WriteByte('=')
+
+		value := formatValue(record[key])
+		if len(value) == 0 || strings.ContainsAny(value, " =\"\t\n") {
+			value = strconv.Quote(value)
+		}
+		w.out.WriteString(value)
+	}
+	return w.out.WriteByte('\n')
+}
+
+func (w *logfmtWriter) Flush() error {
+	return w.out.Flush()
+}
+
+// formatValue converts a typed value to its textual representation
+func formatValue(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return fmt.Sprint(v)
+	}
+}
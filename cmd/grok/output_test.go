@@ -0,0 +1,57 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/trivago/tgo/ttesting"
+)
+
+func TestRecordWriters(t *testing.T) {
+	expect := ttesting.NewExpect(t)
+	records := []map[string]interface{}{
+		{"client": "10.0.0.1", "bytes": 512, "duration": 0.25},
+		{"client": "10.0.0.2", "message": "a \"quoted\" value, with=signs", "empty": ""},
+	}
+	columns := []string{"client", "bytes", "message"}
+
+	for _, test := range []struct {
+		format   string
+		expected string
+	}{
+		{"ndjson", "{\"bytes\":512,\"client\":\"10.0.0.1\",\"duration\":0.25}\n" +
+			"{\"client\":\"10.0.0.2\",\"empty\":\"\",\"message\":\"a \\\"quoted\\\" value, with=signs\"}\n"},
+		{"JSON", "{\"bytes\":512,\"client\":\"10.0.0.1\",\"duration\":0.25}\n" +
+			"{\"client\":\"10.0.0.2\",\"empty\":\"\",\"message\":\"a \\\"quoted\\\" value, with=signs\"}\n"},
+		{"csv", "client,bytes,message\n" +
+			"10.0.0.1,512,\n" +
+			"10.0.0.2,,\"a \"\"quoted\"\" value, with=signs\"\n"},
+		{"tsv", "client\tbytes\tmessage\n" +
+			"10.0.0.1\t512\t\n" +
+			"10.0.0.2\t\t\"a \"\"quoted\"\" value, with=signs\"\n"},
+		{"logfmt", "bytes=512 client=10.0.0.1 duration=0.25\n" +
+			"client=10.0.0.2 empty=\"\" message=\"a \\\"quoted\\\" value, with=signs\"\n"},
+	} {
+		out := &bytes.Buffer{}
+		writer, err := newRecordWriter(test.format, out, columns)
+		expect.NoError(err)
+		for _, record := range records {
+			expect.NoError(writer.Write(record))
+		}
+		expect.NoError(writer.Flush())
+		expect.Equal(test.expected, out.String())
+	}
+
+	_, err := newRecordWriter("xml", &bytes.Buffer{}, columns)
+	expect.NotNil(err)
+}
+
+func TestFormatValue(t *testing.T) {
+	expect := ttesting.NewExpect(t)
+
+	expect.Equal("text", formatValue("text"))
+	expect.Equal("0.1", formatValue(0.1))
+	expect.Equal("1000000", formatValue(1e6))
+	expect.Equal("42", formatValue(42))
+	expect.Equal("true", formatValue(true))
+}
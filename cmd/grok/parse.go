@@ -0,0 +1,281 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/rtkjweeks/grok-go-pcre"
+)
+
+// parseStats counts the processed lines
+type parseStats struct {
+	lines     int
+	matched   int
+	unmatched int
+	failed    int
+}
+
+func (stats parseStats) print(out io.Writer) {
+	rate := 0.0
+	if stats.lines > 0 {
+		rate = 100 * float64(stats.matched) / float64(stats.lines)
+	}
+	fmt.Fprintf(out, "lines: %d, matched: %d (%.2f%%), unmatched: %d, conversion errors: %d\n",
+		stats.lines, stats.matched, rate, stats.unmatched, stats.failed)
+}
+
+// runParse implements the default command, parsing files or stdin
+func runParse(args []string) error {
+	flags := flag.NewFlagSet("parse", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: grok -p <expression> [flags] [file ...]")
+		fmt.Fprintln(flags.Output(), "\nParses the given files, or stdin if none or \"-\" is given.")
+		flags.PrintDefaults()
+	}
+	patternFlags := addPatternFlags(flags)
+	expression := flags.String("p", "", "grok expression to match, e.g. %{COMBINEDAPACHELOG}")
+	format := flags.String("format", "ndjson", "output format: ndjson, csv, tsv or logfmt")
+	typed := flags.Bool("typed", false, "convert fields with type hints, e.g. %{NUMBER:bytes:int}")
+	nested := flags.Bool("nested", false, "output field paths like [client][ip] or client.ip as nested objects, ndjson only")
+	expand := flags.String("expand", "", "comma separated fields expanded into sub-fields, <field>=<kv|sd|json>, e.g. message=kv")
+	redact := flags.String("redact", "", "comma separated redaction rules <field or pattern>=<drop|mask|hash|truncate:n|ipprefix>, hash is keyed with $GROK_REDACT_KEY")
+	redactLine := flags.Bool("redact-line", false, "print the matched lines with the redacted captures replaced instead of the fields")
+	unmatchedPath := flags.String("unmatched", "", "write lines that do not match to this file")
+	showStats := flags.Bool("stats", false, "print match statistics to stderr at exit")
+	workers := flags.Int("workers", runtime.NumCPU(), "number of parallel matchers")
+	flags.Parse(args)
+
+	if len(*expression) == 0 {
+		flags.Usage()
+		return errors.New("no expression given, use -p")
+	}
+
+	g, err := patternFlags.newGrok()
+	if err != nil {
+		return err
+	}
+	compiled, err := g.Compile(*expression)
+	if err != nil {
+		return err
+	}
+
+	processors, err := parseProcessors(*expand)
+	if err != nil {
+		return err
+	}
+	rules, err := parseRedactRules(*redact)
+	if err != nil {
+		return err
+	}
+	var redactor *grok.Redactor
+	if len(rules) > 0 {
+		if redactor, err = compiled.NewRedactor(rules...); err != nil {
+			return err
+		}
+	} else if *redactLine {
+		return errors.New("-redact-line requires -redact")
+	}
+
+	if *nested && *format != "ndjson" && *format != "json" {
+		return errors.New("-nested requires the ndjson format")
+	}
+
+	out := bufio.NewWriter(stdout)
+	defer out.Flush()
+	writer, err := newRecordWriter(*format, out, compiled.FieldNames())
+	if err != nil {
+		return err
+	}
+
+	var unmatched *bufio.Writer
+	if len(*unmatchedPath) > 0 {
+		file, err := os.Create(*unmatchedPath)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		unmatched = bufio.NewWriter(file)
+		defer unmatched.Flush()
+	}
+
+	pipeline := compiled.NewPipeline(grok.PipelineConfig{Workers: *workers, PreserveOrder: true, Redactor: redactor})
+	readErr := make(chan error, 1)
+	go func() {
+		readErr <- submitInputs(pipeline, flags.Args())
+		pipeline.Close()
+	}()
+
+	stats := parseStats{}
+	for result := range pipeline.Results() {
+		stats.lines++
+		if !result.Matched {
+			stats.unmatched++
+			if unmatched != nil {
+				unmatched.WriteString(result.Text)
+				unmatched.WriteByte('\n')
+			}
+			continue
+		}
+
+		if *redactLine {
+			stats.matched++
+			out.WriteString(result.Redacted)
+			out.WriteByte('\n')
+			continue
+		}
+
+		record, err := toRecord(compiled, result.Values, *typed, patternFlags.removeEmpty)
+		if err == nil {
+			err = grok.ApplyProcessors(record, processors...)
+		}
+		if err == nil && *nested {
+			record, err = grok.Nest(record)
+		}
+		if err != nil {
+			stats.failed++
+			fmt.Fprintf(os.Stderr, "line %d: %s\n", result.Index+1, err)
+			continue
+		}
+		stats.matched++
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+
+	if err := writer.Flush(); err != nil {
+		return err
+	}
+	if *showStats {
+		stats.print(os.Stderr)
+	}
+	return <-readErr
+}
+
+// parseProcessors parses the fields given with -expand, e.g.
+// "message=kv,sd=sd"
+func parseProcessors(spec string) ([]grok.ProcessField, error) {
+	processors := []grok.ProcessField{}
+	for _, entry := range strings.Split(spec, ",") {
+		if entry = strings.TrimSpace(entry); len(entry) == 0 {
+			continue
+		}
+		field := grok.ProcessField{Field: entry}
+		if separator := strings.LastIndexByte(entry, '='); separator >= 0 {
+			field.Field = entry[:separator]
+			switch entry[separator+1:] {
+			case "kv":
+				field.Processor = grok.KeyValueParser(grok.KeyValueConfig{})
+			case "sd":
+				field.Processor = grok.ParseStructuredData
+			case "json":
+				field.Processor = grok.ParseJSON
+			}
+		}
+		if field.Processor == nil {
+			return nil, fmt.Errorf("invalid field to expand %s, must be <field>=<kv|sd|json>", entry)
+		}
+		processors = append(processors, field)
+	}
+	return processors, nil
+}
+
+// parseRedactRules parses the rules given with -redact, e.g.
+// "clientip=ipprefix,EMAILADDRESS=hash". A name selects both the fields and
+// the pattern of that name.
+func parseRedactRules(spec string) ([]grok.RedactRule, error) {
+	rules := []grok.RedactRule{}
+	for _, entry := range strings.Split(spec, ",") {
+		if entry = strings.TrimSpace(entry); len(entry) == 0 {
+			continue
+		}
+		name, action := entry, ""
+		if separator := strings.IndexByte(entry, '='); separator >= 0 {
+			name, action = entry[:separator], entry[separator+1:]
+		}
+
+		rule := grok.RedactRule{Fields: []string{name}, Patterns: []string{name}}
+		switch {
+		case action == "drop":
+			rule.Action = grok.RedactDrop
+		case action == "mask":
+			rule.Action = grok.RedactMask
+		case action == "hash":
+			rule.Action = grok.RedactHash
+			rule.Key = []byte(os.Getenv("GROK_REDACT_KEY"))
+		case action == "ipprefix":
+			rule.Action = grok.RedactIPPrefix
+		case strings.HasPrefix(action, "truncate:"):
+			keep, err := strconv.Atoi(strings.TrimPrefix(action, "truncate:"))
+			if err != nil {
+				return nil, fmt.Errorf("invalid redaction rule %s: %s", entry, err)
+			}
+			rule.Action = grok.RedactTruncate
+			rule.Keep = keep
+		default:
+			return nil, fmt.Errorf("invalid redaction rule %s, the action must be drop, mask, hash, truncate:<n> or ipprefix", entry)
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// submitInputs feeds all lines of the given files into the pipeline.
+// Stdin is read if no files are given.
+func submitInputs(pipeline *grok.Pipeline, paths []string) error {
+	if len(paths) == 0 {
+		paths = []string{"-"}
+	}
+
+	for _, path := range paths {
+		if err := submitFile(pipeline, path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// submitFile feeds all lines of a file, or stdin for "-", into the pipeline
+func submitFile(pipeline *grok.Pipeline, path string) error {
+	input := io.Reader(os.Stdin)
+	if path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		input = file
+	}
+
+	scanner := bufio.NewScanner(input)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		pipeline.Submit(scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("%s: %s", path, err)
+	}
+	return nil
+}
+
+// toRecord converts matched values to an output record, dropping unnamed
+// groups and converting types if requested.
+func toRecord(compiled *grok.CompiledGrok, values map[string]string, typed, removeEmpty bool) (map[string]interface{}, error) {
+	if typed {
+		return compiled.ConvertTypes(values)
+	}
+
+	record := make(map[string]interface{}, len(values))
+	for key, value := range values {
+		if len(key) > 0 && !(removeEmpty && len(value) == 0) {
+			record[key] = value
+		}
+	}
+	return record, nil
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rtkjweeks/grok-go-pcre"
+	"github.com/trivago/tgo/ttesting"
+)
+
+// runCommand runs a command and returns what it wrote to stdout
+func runCommand(t *testing.T, run func(args []string) error, args ...string) (string, error) {
+	buffer := &bytes.Buffer{}
+	stdout = buffer
+	defer func() { stdout = os.Stdout }()
+
+	err := run(args)
+	return buffer.String(), err
+}
+
+// writeLines writes a temporary input file
+func writeLines(t *testing.T, lines ...string) string {
+	path := filepath.Join(t.TempDir(), "input.log")
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestParse(t *testing.T) {
+	expect := ttesting.NewExpect(t)
+	input := writeLines(t, "GET 200", "nope", "PUT 201")
+	unmatched := filepath.Join(t.TempDir(), "unmatched.log")
+
+	output, err := runCommand(t, runParse, "-p", "%{WORD:verb} %{INT:code:int}", "-named-only", "-typed", "-workers", "2", "-unmatched", unmatched, input)
+	expect.NoError(err)
+	expect.Equal("{\"code\":200,\"verb\":\"GET\"}\n{\"code\":201,\"verb\":\"PUT\"}\n", output)
+	data, err := os.ReadFile(unmatched)
+	expect.NoError(err)
+	expect.Equal("nope\n", string(data))
+
+	output, err = runCommand(t, runParse, "-p", "%{WORD:verb} %{INT:code}", "-named-only", "-format", "csv", input)
+	expect.NoError(err)
+	expect.Equal("verb,code\nGET,200\nPUT,201\n", output)
+
+	_, err = runCommand(t, runParse, "-p", "%{UNKNOWN}", input)
+	expect.NotNil(err)
+	_, err = runCommand(t, runParse, "-p", "%{WORD}", "-format", "csv", "-nested", input)
+	expect.NotNil(err)
+}
+
+func TestParsePatternsDir(t *testing.T) {
+	expect := ttesting.NewExpect(t)
+	input := writeLines(t, "abc", "123")
+	dir := t.TempDir()
+	expect.NoError(os.WriteFile(filepath.Join(dir, "patterns"), []byte("WORD \\d+\nMYW %{WORD}\n"), 0644))
+
+	// Pattern files replace the default patterns of the same name
+	output, err := runCommand(t, runParse, "-p", "%{MYW:w}", "-patterns-dir", dir, "-named-only", input)
+	expect.NoError(err)
+	expect.Equal("{\"w\":\"123\"}\n", output)
+}
+
+func TestParseRedact(t *testing.T) {
+	expect := ttesting.NewExpect(t)
+	input := writeLines(t, "10.1.2.3 alice", "-")
+
+	// Captures enclosing the redacted IP, like client, and the ones inside it
+	// contain the redacted value, too. The default IPORHOST is used, as the
+	// one of the grok pack tries HOSTNAME first.
+	output, err := runCommand(t, runParse, "-p", "%{IPORHOST:client} %{USER:user}", "-packs", "none", "-redact", "IP=ipprefix", input)
+	expect.NoError(err)
+	expect.False(strings.Contains(output, "10.1.2.3"))
+	record := map[string]string{}
+	expect.NoError(json.Unmarshal([]byte(output), &record))
+	expect.Equal("10.1.2.0", record["client"])
+	expect.Equal("10.1.2.0", record["IPV4"])
+	expect.Equal("10.1.2.0", record["IP"])
+	expect.Equal("alice", record["user"])
+
+	output, err = runCommand(t, runParse, "-p", "%{IPORHOST:client} %{USER:user}", "-redact", "client=ipprefix,user=mask", "-redact-line", input)
+	expect.NoError(err)
+	expect.Equal("10.1.2.0 *****\n", output)
+
+	// IP has no capture of its own with -named-only
+	_, err = runCommand(t, runParse, "-p", "%{IPORHOST:client} %{USER:user}", "-packs", "none", "-named-only", "-redact", "IP=ipprefix", input)
+	expect.NotNil(err)
+	_, err = runCommand(t, runParse, "-p", "%{IPORHOST:client}", "-redact-line", input)
+	expect.NotNil(err)
+}
+
+func TestParseRedactRules(t *testing.T) {
+	expect := ttesting.NewExpect(t)
+	t.Setenv("GROK_REDACT_KEY", "secret")
+
+	for _, test := range []struct {
+		spec     string
+		expected []grok.RedactRule
+	}{
+		{"", []grok.RedactRule{}},
+		{"clientip=ipprefix", []grok.RedactRule{
+			{Fields: []string{"clientip"}, Patterns: []string{"clientip"}, Action: grok.RedactIPPrefix},
+		}},
+		{" user=drop , EMAILADDRESS=hash,", []grok.RedactRule{
+			{Fields: []string{"user"}, Patterns: []string{"user"}, Action: grok.RedactDrop},
+			{Fields: []string{"EMAILADDRESS"}, Patterns: []string{"EMAILADDRESS"}, Action: grok.RedactHash, Key: []byte("secret")},
+		}},
+		{"message=truncate:10,card=mask", []grok.RedactRule{
+			{Fields: []string{"message"}, Patterns: []string{"message"}, Action: grok.RedactTruncate, Keep: 10},
+			{Fields: []string{"card"}, Patterns: []string{"card"}, Action: grok.RedactMask},
+		}},
+	} {
+		rules, err := parseRedactRules(test.spec)
+		expect.NoError(err)
+		expect.Equal(test.expected, rules)
+	}
+
+	for _, spec := range []string{"user", "user=shred", "message=truncate:x", "message=truncate"} {
+		_, err := parseRedactRules(spec)
+		expect.NotNil(err)
+	}
+}
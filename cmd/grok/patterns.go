@@ -0,0 +1,186 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/rtkjweeks/grok-go-pcre"
+	"github.com/rtkjweeks/grok-go-pcre/patterns"
+)
+
+// patternOptions holds the flags controlling which patterns are loaded
+type patternOptions struct {
+	packs       string
+	patternsDir string
+	namedOnly   bool
+	removeEmpty bool
+	engine      string
+	bundle      string
+	normalize   string
+	ecs         bool
+	// collectStats is set by commands reporting match statistics
+	collectStats bool
+}
+
+// addPatternFlags registers the pattern related flags on a flag set
+func addPatternFlags(flags *flag.FlagSet) *patternOptions {
+	options := &patternOptions{}
+	flags.StringVar(&options.packs, "packs", "all", "comma separated list of bundled pattern packs to load, \"all\" or \"none\" ("+strings.Join(packNames(), ", ")+")")
+	flags.StringVar(&options.patternsDir, "patterns-dir", "", "file or directory with additional patterns in logstash format")
+	flags.BoolVar(&options.namedOnly, "named-only", false, "only capture fields with an explicit name, e.g. %{IP:client}")
+	flags.BoolVar(&options.removeEmpty, "remove-empty", false, "do not output fields with empty values")
+	flags.StringVar(&options.bundle, "bundle", "", "file caching the resolved patterns, it is rebuilt if the patterns change")
+	flags.BoolVar(&options.ecs, "ecs", false, "use Elastic Common Schema field names, selecting the ECS variant of packs that have one")
+	flags.StringVar(&options.normalize, "normalize", "", "comma separated field name normalizers applied in order: snake, lower or prefix=<prefix>")
+	flags.StringVar(&options.engine, "engine", grok.DefaultEngine.Name(), "regexp engine ("+strings.Join(grok.EngineNames(), ", ")+")")
+	return options
+}
+
+// patternSource is a named set of patterns, i.e. a pack or a pattern file
+type patternSource struct {
+	name     string
+	patterns map[string]string
+}
+
+// sources returns the selected packs and pattern files in the order they
+// are merged.
+func (options *patternOptions) sources() ([]patternSource, error) {
+	var selected []string
+	switch options.packs {
+	case "all":
+		selected = packNames()
+	case "none", "":
+	default:
+		selected = strings.Split(options.packs, ",")
+	}
+
+	sources := make([]patternSource, 0, len(selected)+1)
+	for _, name := range selected {
+		name = strings.TrimSpace(name)
+		pack, known := patterns.Packs[name]
+		if !known {
+			return nil, fmt.Errorf("unknown pattern pack %s", name)
+		}
+		if ecsPack, hasECS := patterns.ECSPacks[name]; options.ecs && hasECS {
+			pack = ecsPack
+		}
+		sources = append(sources, patternSource{name, pack})
+	}
+
+	if len(options.patternsDir) > 0 {
+		custom, err := grok.LoadPatterns(options.patternsDir)
+		if err != nil {
+			return nil, err
+		}
+		sources = append(sources, patternSource{options.patternsDir, custom})
+	}
+	return sources, nil
+}
+
+// corpus returns the samples of a pack, using the ECS variant if -ecs is set.
+// Nil is returned for sources that are not a pack, e.g. -patterns-dir.
+func (options *patternOptions) corpus(pack string) ([]byte, error) {
+	if _, isPack := patterns.Packs[pack]; !isPack {
+		return nil, nil
+	}
+	if _, hasECS := patterns.ECSPacks[pack]; options.ecs && hasECS {
+		return patterns.ECSCorpus(pack)
+	}
+	return patterns.Corpus(pack)
+}
+
+// patterns merges the selected packs and pattern files into a single map.
+// Pattern files take precedence over packs.
+func (options *patternOptions) patterns() (map[string]string, error) {
+	sources, err := options.sources()
+	if err != nil {
+		return nil, err
+	}
+
+	merged := make(map[string]string)
+	for _, source := range sources {
+		for key, expression := range source.patterns {
+			merged[key] = expression
+		}
+	}
+	return merged, nil
+}
+
+// newGrok creates a grok instance with the default patterns and the
+// patterns selected by the flags. The selected patterns replace default
+// patterns of the same name.
+func (options *patternOptions) newGrok() (*grok.Grok, error) {
+	merged, err := options.patterns()
+	if err != nil {
+		return nil, err
+	}
+	defaults := grok.DefaultPatterns
+	if options.ecs {
+		defaults = grok.ECSDefaultPatterns
+	}
+	definitions := make(map[string]string, len(defaults)+len(merged))
+	for name, definition := range defaults {
+		definitions[name] = definition
+	}
+	for name, definition := range merged {
+		definitions[name] = definition
+	}
+	engine, err := grok.LookupEngine(options.engine)
+	if err != nil {
+		return nil, err
+	}
+	normalize, err := options.normalizer()
+	if err != nil {
+		return nil, err
+	}
+	config := grok.Config{
+		NamedCapturesOnly:   options.namedOnly,
+		RemoveEmptyValues:   options.removeEmpty,
+		Patterns:            definitions,
+		SkipDefaultPatterns: true,
+		Engine:              engine,
+		NormalizeName:       normalize,
+		ECSCompatibility:    options.ecs,
+		CollectStats:        options.collectStats,
+	}
+	if len(options.bundle) > 0 {
+		return grok.NewFromBundleFile(options.bundle, config)
+	}
+	return grok.New(config)
+}
+
+// normalizer returns the field name normalizer selected by -normalize or
+// nil if none is selected
+func (options *patternOptions) normalizer() (grok.NameNormalizer, error) {
+	normalizers := []grok.NameNormalizer{}
+	for _, name := range strings.Split(options.normalize, ",") {
+		name = strings.TrimSpace(name)
+		switch {
+		case len(name) == 0:
+		case name == "snake":
+			normalizers = append(normalizers, grok.SnakeCase)
+		case name == "lower":
+			normalizers = append(normalizers, grok.LowerCase)
+		case strings.HasPrefix(name, "prefix="):
+			normalizers = append(normalizers, grok.PrefixNames(strings.TrimPrefix(name, "prefix=")))
+		default:
+			return nil, fmt.Errorf("unknown normalizer %s, must be snake, lower or prefix=<prefix>", name)
+		}
+	}
+	if len(normalizers) == 0 {
+		return nil, nil
+	}
+	return grok.ChainNormalizers(normalizers...), nil
+}
+
+// packNames returns the sorted names of all bundled pattern packs
+func packNames() []string {
+	names := make([]string, 0, len(patterns.Packs))
+	for name := range patterns.Packs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
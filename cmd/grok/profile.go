@@ -0,0 +1,135 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/rtkjweeks/grok-go-pcre"
+)
+
+// runProfile reports the match times and the most expensive parts of
+// expressions
+func runProfile(args []string) error {
+	flags := flag.NewFlagSet("profile", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: grok profile [-p <expression>] [flags] [file ...]")
+		fmt.Fprintln(flags.Output(), "\nMatches the non-empty lines of the given files, or stdin, against the expression and")
+		fmt.Fprintln(flags.Output(), "reports the match times, backtracking steps and the most expensive parts of it.")
+		fmt.Fprintln(flags.Output(), "Without an expression, the patterns of each selected pack are profiled with")
+		fmt.Fprintln(flags.Output(), "the samples shipped with the pack, the most expensive pattern first.")
+		flags.PrintDefaults()
+	}
+	patternFlags := addPatternFlags(flags)
+	expression := flags.String("p", "", "grok expression to profile")
+	maxSamples := flags.Int("n", 1000, "maximum number of sample lines to use")
+	top := flags.Int("top", 10, "number of patterns reported when profiling packs")
+	costs := flags.Int("costs", 5, "number of expensive components reported per expression")
+	flags.Parse(args)
+
+	g, err := patternFlags.newGrok()
+	if err != nil {
+		return err
+	}
+
+	out := bufio.NewWriter(stdout)
+	defer out.Flush()
+
+	if len(*expression) > 0 {
+		paths := flags.Args()
+		if len(paths) == 0 {
+			paths = []string{"-"}
+		}
+		samples := []string{}
+		for _, path := range paths {
+			if samples, err = readSamples(path, samples, *maxSamples); err != nil {
+				return err
+			}
+		}
+		result, err := g.Profile(*expression, samples)
+		if err != nil {
+			return err
+		}
+		printProfile(out, result, *costs)
+		return nil
+	}
+
+	sources, err := patternFlags.sources()
+	if err != nil {
+		return err
+	}
+	results := []*grok.ProfileResult{}
+	for _, source := range sources {
+		data, err := patternFlags.corpus(source.name)
+		if err != nil {
+			return fmt.Errorf("%s: %s", source.name, err)
+		}
+		if data == nil {
+			continue // not a pack
+		}
+		corpus, err := grok.ReadCorpus(bytes.NewReader(data))
+		if err != nil {
+			return fmt.Errorf("%s: %s", source.name, err)
+		}
+		packResults, err := grok.ProfileCorpus(g, corpus)
+		if err != nil {
+			return fmt.Errorf("%s: %s", source.name, err)
+		}
+		results = append(results, packResults...)
+	}
+
+	sortProfiles(results)
+	for i, result := range results {
+		if i == *top {
+			break
+		}
+		if i > 0 {
+			fmt.Fprintln(out)
+		}
+		printProfile(out, result, *costs)
+	}
+	return nil
+}
+
+// sortProfiles orders the results of several packs like ProfileCorpus
+func sortProfiles(results []*grok.ProfileResult) {
+	countedSteps := true
+	for _, result := range results {
+		countedSteps = countedSteps && result.Steps >= 0
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		if countedSteps {
+			return results[i].Steps > results[j].Steps
+		}
+		return results[i].Average > results[j].Average
+	})
+}
+
+// printProfile prints a profile with up to maxCosts components
+func printProfile(out io.Writer, result *grok.ProfileResult, maxCosts int) {
+	fmt.Fprintf(out, "%s: %d samples, %d matched\n", result.Expression, result.Samples, result.Matched)
+	if result.Samples == 0 {
+		return
+	}
+	fmt.Fprintf(out, "  time:    avg %v, p99 %v, max %v\n", result.Average, result.P99, result.Max)
+	if result.Steps >= 0 {
+		fmt.Fprintf(out, "  steps:   avg %.0f, max %d, %d over the limit\n", result.Steps, result.MaxSteps, result.LimitHits)
+	}
+	fmt.Fprintf(out, "  slowest: %s\n", result.Slowest)
+
+	for i, cost := range result.Costs {
+		if i == maxCosts || cost.Share == 0 {
+			break
+		}
+		component := strings.Join(append(append([]string{}, cost.Path...), cost.Component), " > ")
+		steps := ""
+		if cost.Steps >= 0 {
+			steps = fmt.Sprintf(", %.0f steps", cost.Steps)
+		}
+		fmt.Fprintf(out, "  %5.1f%%  %s (%v%s)\n", cost.Share*100, component, cost.Time, steps)
+	}
+}
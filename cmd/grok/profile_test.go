@@ -0,0 +1,29 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/trivago/tgo/ttesting"
+)
+
+func TestProfilePacks(t *testing.T) {
+	expect := ttesting.NewExpect(t)
+	dir := t.TempDir()
+	expect.NoError(os.WriteFile(filepath.Join(dir, "custom"), []byte("CUSTOM %{WORD}\n"), 0644))
+	options := &patternOptions{patternsDir: dir}
+
+	// Pattern files have no corpus and are skipped
+	data, err := options.corpus(dir)
+	expect.NoError(err)
+	expect.Nil(data)
+	data, err = options.corpus("redis")
+	expect.NoError(err)
+	expect.Greater(len(data), 0)
+
+	output, err := runCommand(t, runProfile, "-packs", "redis", "-patterns-dir", dir, "-n", "5")
+	expect.NoError(err)
+	expect.True(strings.Contains(output, "REDISLOG"))
+}
@@ -0,0 +1,288 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rtkjweeks/grok-go-pcre"
+	"github.com/trivago/tgo"
+	"github.com/trivago/tgo/thealthcheck"
+)
+
+// Metrics of the serve command, see tgo.Metric
+const (
+	metricRequests  = "grok.requests"
+	metricLines     = "grok.lines"
+	metricMatched   = "grok.matched"
+	metricUnmatched = "grok.unmatched"
+	metricErrors    = "grok.errors"
+)
+
+const (
+	// maxCachedExpressions limits the number of compiled expressions kept
+	// by the server
+	maxCachedExpressions = 1024
+	// readHeaderTimeout limits the time clients may take to send the
+	// headers of a request
+	readHeaderTimeout = 10 * time.Second
+)
+
+// parseServer answers the HTTP requests of the serve command
+type parseServer struct {
+	grok        *grok.Grok
+	removeEmpty bool
+	maxBody     int64
+
+	lock     sync.RWMutex
+	compiled map[string]*grok.CompiledGrok
+}
+
+// parseInput is a line of a NDJSON request to /parse
+type parseInput struct {
+	Message string `json:"message"`
+}
+
+// parseOutput is a line of the response of /parse
+type parseOutput struct {
+	Matched bool                   `json:"matched"`
+	Fields  map[string]interface{} `json:"fields,omitempty"`
+	Error   string                 `json:"error,omitempty"`
+}
+
+// runServe implements the serve command, parsing lines posted via HTTP
+func runServe(args []string) error {
+	flags := flag.NewFlagSet("serve", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: grok serve [flags]")
+		fmt.Fprintln(flags.Output(), "\nServes the selected patterns via HTTP:")
+		fmt.Fprintln(flags.Output(), "  POST /parse?pattern=<name> or ?expression=<expression>[&typed=true]")
+		fmt.Fprintln(flags.Output(), "       parses the lines of the body, or the \"message\" of each object if the")
+		fmt.Fprintln(flags.Output(), "       content type is application/x-ndjson, and returns one object per line")
+		fmt.Fprintln(flags.Output(), "  GET  /patterns lists the loaded patterns")
+		fmt.Fprintln(flags.Output(), "  GET  /health and /metrics report the state of the server")
+		fmt.Fprintln(flags.Output(), "  GET  /metrics/patterns reports match statistics in the Prometheus text format")
+		flags.PrintDefaults()
+	}
+	patternFlags := addPatternFlags(flags)
+	listen := flags.String("listen", ":8080", "address to listen on")
+	healthListen := flags.String("health-listen", "", "additional address serving /grok/health via thealthcheck, e.g. :8081")
+	maxBody := flags.Int64("max-body", 10<<20, "maximum size of a request body in bytes")
+	flags.Parse(args)
+
+	patternFlags.collectStats = true
+	g, err := patternFlags.newGrok()
+	if err != nil {
+		return err
+	}
+	server := newParseServer(g, patternFlags.removeEmpty, *maxBody)
+	initMetrics()
+
+	if len(*healthListen) > 0 {
+		thealthcheck.Configure(*healthListen)
+		thealthcheck.AddEndpoint("/grok/health", server.health)
+		go thealthcheck.Start()
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/parse", server.handleParse)
+	mux.HandleFunc("/patterns", server.handlePatterns)
+	mux.HandleFunc("/health", server.handleHealth)
+	mux.HandleFunc("/metrics", server.handleMetrics)
+	mux.Handle("/metrics/patterns", grok.PrometheusHandler(server.stats))
+	httpServer := &http.Server{
+		Addr:              *listen,
+		Handler:           mux,
+		ReadHeaderTimeout: readHeaderTimeout,
+	}
+	return httpServer.ListenAndServe()
+}
+
+// newParseServer returns a server matching with the given grok
+func newParseServer(g *grok.Grok, removeEmpty bool, maxBody int64) *parseServer {
+	return &parseServer{
+		grok:        g,
+		removeEmpty: removeEmpty,
+		maxBody:     maxBody,
+		compiled:    make(map[string]*grok.CompiledGrok),
+	}
+}
+
+// initMetrics enables tgo.Metric and registers the metrics of the server
+func initMetrics() {
+	tgo.EnableGlobalMetrics()
+	tgo.Metric.InitSystemMetrics()
+	for _, name := range []string{metricRequests, metricLines, metricMatched, metricUnmatched, metricErrors} {
+		tgo.Metric.New(name)
+	}
+}
+
+// expression returns the compiled expression selected by the pattern or
+// expression parameter of a request
+func (server *parseServer) expression(request *http.Request) (*grok.CompiledGrok, error) {
+	query := request.URL.Query()
+	expression := query.Get("expression")
+	if name := query.Get("pattern"); len(name) > 0 {
+		if _, known := server.grok.Definition(name); !known {
+			return nil, fmt.Errorf("unknown pattern %s", name)
+		}
+		expression = "%{" + name + "}"
+	}
+	if len(expression) == 0 {
+		return nil, errors.New("no pattern or expression given")
+	}
+
+	server.lock.RLock()
+	compiled, cached := server.compiled[expression]
+	server.lock.RUnlock()
+	if cached {
+		return compiled, nil
+	}
+
+	compiled, err := server.grok.Compile(expression)
+	if err != nil {
+		return nil, err
+	}
+	server.lock.Lock()
+	if len(server.compiled) < maxCachedExpressions {
+		server.compiled[expression] = compiled
+	}
+	server.lock.Unlock()
+	return compiled, nil
+}
+
+// handleParse parses the lines of the request body
+func (server *parseServer) handleParse(response http.ResponseWriter, request *http.Request) {
+	if request.Method != http.MethodPost {
+		http.Error(response, "use POST", http.StatusMethodNotAllowed)
+		return
+	}
+	tgo.Metric.Inc(metricRequests)
+
+	compiled, err := server.expression(request)
+	if err != nil {
+		tgo.Metric.Inc(metricErrors)
+		http.Error(response, err.Error(), http.StatusBadRequest)
+		return
+	}
+	typed := request.URL.Query().Get("typed") == "true"
+	ndjson := strings.HasPrefix(request.Header.Get("Content-Type"), "application/x-ndjson")
+
+	response.Header().Set("Content-Type", "application/x-ndjson")
+	out := bufio.NewWriter(response)
+	defer out.Flush()
+	encoder := json.NewEncoder(out)
+
+	scanner := bufio.NewScanner(http.MaxBytesReader(response, request.Body, server.maxBody))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if ndjson {
+			input := parseInput{}
+			if err := json.Unmarshal([]byte(line), &input); err != nil {
+				tgo.Metric.Inc(metricErrors)
+				encoder.Encode(parseOutput{Error: err.Error()})
+				continue
+			}
+			line = input.Message
+		}
+		encoder.Encode(server.parse(compiled, line, typed))
+	}
+	if err := scanner.Err(); err != nil && err != io.EOF {
+		tgo.Metric.Inc(metricErrors)
+		encoder.Encode(parseOutput{Error: err.Error()})
+	}
+}
+
+// parse matches a single line and updates the metrics
+func (server *parseServer) parse(compiled *grok.CompiledGrok, line string, typed bool) parseOutput {
+	tgo.Metric.Inc(metricLines)
+	matched, values := compiled.MatchAgainst(line)
+	if !matched {
+		tgo.Metric.Inc(metricUnmatched)
+		return parseOutput{}
+	}
+
+	record, err := toRecord(compiled, values, typed, server.removeEmpty)
+	if err != nil {
+		tgo.Metric.Inc(metricErrors)
+		return parseOutput{Error: err.Error()}
+	}
+	tgo.Metric.Inc(metricMatched)
+	return parseOutput{Matched: true, Fields: record}
+}
+
+// stats returns the match statistics of the cached expressions
+func (server *parseServer) stats() []grok.MatchStats {
+	server.lock.RLock()
+	defer server.lock.RUnlock()
+
+	stats := make([]grok.MatchStats, 0, len(server.compiled))
+	for _, compiled := range server.compiled {
+		stats = append(stats, compiled.Stats())
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].Name < stats[j].Name
+	})
+	return stats
+}
+
+// handlePatterns lists the loaded patterns and their definitions
+func (server *parseServer) handlePatterns(response http.ResponseWriter, request *http.Request) {
+	definitions := make(map[string]string)
+	for _, name := range server.grok.PatternNames() {
+		definitions[name], _ = server.grok.Definition(name)
+	}
+	writeJSON(response, definitions)
+}
+
+// health reports if the server can compile and match expressions, see
+// thealthcheck.CallbackFunc
+func (server *parseServer) health() (int, string) {
+	compiled, err := server.grok.Compile("%{INT:value}")
+	if err != nil {
+		return thealthcheck.StatusServiceUnavailable, err.Error()
+	}
+	if !compiled.MatchString("1") {
+		return thealthcheck.StatusServiceUnavailable, "test expression did not match"
+	}
+	return thealthcheck.StatusOK, "OK"
+}
+
+// handleHealth answers health checks on the main address
+func (server *parseServer) handleHealth(response http.ResponseWriter, request *http.Request) {
+	code, body := server.health()
+	response.WriteHeader(code)
+	fmt.Fprintln(response, body)
+}
+
+// handleMetrics returns the metrics of tgo.Metric as JSON
+func (server *parseServer) handleMetrics(response http.ResponseWriter, request *http.Request) {
+	tgo.Metric.UpdateSystemMetrics()
+	data, err := tgo.Metric.Dump()
+	if err != nil {
+		http.Error(response, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	response.Header().Set("Content-Type", "application/json")
+	response.Write(data)
+}
+
+// writeJSON writes a value as JSON response
+func writeJSON(response http.ResponseWriter, value interface{}) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		http.Error(response, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	response.Header().Set("Content-Type", "application/json")
+	response.Write(data)
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/rtkjweeks/grok-go-pcre"
+	"github.com/trivago/tgo/ttesting"
+)
+
+// newTestServer returns a server using the default patterns
+func newTestServer(t *testing.T, maxBody int64) *parseServer {
+	g, err := grok.New(grok.Config{NamedCapturesOnly: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	initMetrics()
+	return newParseServer(g, false, maxBody)
+}
+
+// post sends a request to /parse and returns the status and the parsed
+// response lines
+func post(t *testing.T, server *parseServer, query url.Values, contentType, body string) (int, []parseOutput) {
+	request := httptest.NewRequest(http.MethodPost, "/parse?"+query.Encode(), strings.NewReader(body))
+	request.Header.Set("Content-Type", contentType)
+	response := httptest.NewRecorder()
+	server.handleParse(response, request)
+	if response.Code != http.StatusOK {
+		return response.Code, nil
+	}
+
+	outputs := []parseOutput{}
+	for _, line := range strings.Split(strings.TrimSuffix(response.Body.String(), "\n"), "\n") {
+		output := parseOutput{}
+		if err := json.Unmarshal([]byte(line), &output); err != nil {
+			t.Fatalf("%q: %s", line, err)
+		}
+		outputs = append(outputs, output)
+	}
+	return response.Code, outputs
+}
+
+func TestHandleParse(t *testing.T) {
+	expect := ttesting.NewExpect(t)
+	server := newTestServer(t, 1024)
+	query := url.Values{"expression": {"%{WORD:verb} %{INT:code:int}"}, "typed": {"true"}}
+
+	code, outputs := post(t, server, query, "text/plain", "GET 200\nnope\n")
+	expect.Equal(http.StatusOK, code)
+	expect.Equal([]parseOutput{
+		{Matched: true, Fields: map[string]interface{}{"verb": "GET", "code": float64(200)}},
+		{},
+	}, outputs)
+
+	code, outputs = post(t, server, query, "application/x-ndjson", "{\"message\": \"PUT 201\"}\n{\"message\n")
+	expect.Equal(http.StatusOK, code)
+	expect.Equal(2, len(outputs))
+	expect.Equal(map[string]interface{}{"verb": "PUT", "code": float64(201)}, outputs[0].Fields)
+	expect.False(outputs[1].Matched)
+	expect.Greater(len(outputs[1].Error), 0)
+
+	// The expression is compiled once
+	expect.Equal(1, len(server.compiled))
+	expect.Equal(1, len(server.stats()))
+
+	code, outputs = post(t, server, url.Values{"pattern": {"INT"}}, "text/plain", "42")
+	expect.Equal(http.StatusOK, code)
+	expect.True(outputs[0].Matched)
+
+	code, _ = post(t, server, url.Values{"pattern": {"UNKNOWN"}}, "text/plain", "42")
+	expect.Equal(http.StatusBadRequest, code)
+	code, _ = post(t, server, url.Values{"expression": {"%{UNKNOWN}"}}, "text/plain", "42")
+	expect.Equal(http.StatusBadRequest, code)
+	code, _ = post(t, server, url.Values{}, "text/plain", "42")
+	expect.Equal(http.StatusBadRequest, code)
+
+	response := httptest.NewRecorder()
+	server.handleParse(response, httptest.NewRequest(http.MethodGet, "/parse?pattern=INT", nil))
+	expect.Equal(http.StatusMethodNotAllowed, response.Code)
+}
+
+func TestHandleParseLimits(t *testing.T) {
+	expect := ttesting.NewExpect(t)
+
+	// Bodies are cut off after maxBody bytes
+	server := newTestServer(t, 1024)
+	body := strings.Repeat("GET 200\n", 200)
+	code, outputs := post(t, server, url.Values{"pattern": {"INT"}}, "text/plain", body)
+	expect.Equal(http.StatusOK, code)
+	expect.Less(len(outputs), 200)
+	expect.Equal(1024/len("GET 200\n"), len(outputs)-1)
+	expect.Greater(len(outputs[len(outputs)-1].Error), 0)
+
+	// Lines longer than the scanner buffer are reported
+	server = newTestServer(t, 4<<20)
+	code, outputs = post(t, server, url.Values{"pattern": {"INT"}}, "text/plain", "1\n"+strings.Repeat("x", 2<<20)+"\n2\n")
+	expect.Equal(http.StatusOK, code)
+	expect.Equal(2, len(outputs))
+	expect.True(outputs[0].Matched)
+	expect.Greater(len(outputs[1].Error), 0)
+}
+
+func TestHandlePatterns(t *testing.T) {
+	expect := ttesting.NewExpect(t)
+	server := newTestServer(t, 1024)
+
+	response := httptest.NewRecorder()
+	server.handlePatterns(response, httptest.NewRequest(http.MethodGet, "/patterns", nil))
+	expect.Equal(http.StatusOK, response.Code)
+	expect.Equal("application/json", response.Header().Get("Content-Type"))
+
+	definitions := map[string]string{}
+	expect.NoError(json.Unmarshal(response.Body.Bytes(), &definitions))
+	definition, _ := server.grok.Definition("WORD")
+	expect.Equal(definition, definitions["WORD"])
+}
+
+func TestHandleHealth(t *testing.T) {
+	expect := ttesting.NewExpect(t)
+	server := newTestServer(t, 1024)
+
+	response := httptest.NewRecorder()
+	server.handleHealth(response, httptest.NewRequest(http.MethodGet, "/health", nil))
+	expect.Equal(http.StatusOK, response.Code)
+	expect.Equal("OK\n", response.Body.String())
+}
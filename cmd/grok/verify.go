@@ -0,0 +1,83 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/rtkjweeks/grok-go-pcre"
+)
+
+// runVerify checks sample corpora against the selected patterns
+func runVerify(args []string) error {
+	flags := flag.NewFlagSet("verify", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: grok verify [flags] [corpus.json ...]")
+		fmt.Fprintln(flags.Output(), "\nMatches the samples of the given corpus files against the selected patterns.")
+		fmt.Fprintln(flags.Output(), "Without files, the samples shipped with each selected pack are checked.")
+		flags.PrintDefaults()
+	}
+	patternFlags := addPatternFlags(flags)
+	flags.Parse(args)
+
+	failed := false
+	report := func(source string, failures []grok.VerifyError) {
+		for _, err := range failures {
+			failed = true
+			fmt.Fprintf(os.Stdout, "%s: %s\n", source, err)
+		}
+	}
+
+	if flags.NArg() == 0 {
+		sources, err := patternFlags.sources()
+		if err != nil {
+			return err
+		}
+		engine, err := grok.LookupEngine(patternFlags.engine)
+		if err != nil {
+			return err
+		}
+		for _, source := range sources {
+			data, err := patternFlags.corpus(source.name)
+			if err != nil {
+				return fmt.Errorf("%s: %s", source.name, err)
+			}
+			if data == nil {
+				continue // not a pack
+			}
+			corpus, err := grok.ReadCorpus(bytes.NewReader(data))
+			if err != nil {
+				return fmt.Errorf("%s: %s", source.name, err)
+			}
+			g, err := grok.New(grok.Config{NamedCapturesOnly: true, Patterns: source.patterns, Engine: engine, ECSCompatibility: patternFlags.ecs})
+			if err != nil {
+				return fmt.Errorf("%s: %s", source.name, err)
+			}
+			report(source.name, grok.Verify(g, corpus))
+		}
+	} else {
+		g, err := patternFlags.newGrok()
+		if err != nil {
+			return err
+		}
+		for _, path := range flags.Args() {
+			file, err := os.Open(path)
+			if err != nil {
+				return err
+			}
+			corpus, err := grok.ReadCorpus(file)
+			file.Close()
+			if err != nil {
+				return fmt.Errorf("%s: %s", path, err)
+			}
+			report(path, grok.Verify(g, corpus))
+		}
+	}
+
+	if failed {
+		return errors.New("samples failed")
+	}
+	return nil
+}
@@ -0,0 +1,229 @@
+// Command grokgen converts logstash pattern files into Go maps like the
+// ones in the patterns package.
+//
+// Usage:
+//
+//	grokgen -var Redis [flags] file ...
+//
+// Comments, empty lines and the order of the patterns are kept and each
+// pattern is annotated with the file and line it was read from. With
+// -structs, a struct with one field per named capture is generated for
+// every pattern not used by another pattern of the files. It is meant to be
+// run by go generate:
+//
+//	//go:generate go run github.com/rtkjweeks/grok-go-pcre/cmd/grokgen -var Redis -o redis.go redis
+//
+// With -expression, a struct and a parser for a single grok expression are
+// generated instead. The given files and packs provide the patterns the
+// expression refers to.
+//
+//	grokgen -expression '%{ELB_ACCESS_LOG}' -type AccessLog -packs grok,aws -o accesslog.go
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/rtkjweeks/grok-go-pcre"
+	"github.com/rtkjweeks/grok-go-pcre/codegen"
+	"github.com/rtkjweeks/grok-go-pcre/patterns"
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "Usage: grokgen -var name [flags] file ...")
+		fmt.Fprintln(flag.CommandLine.Output(), "       grokgen -expression expression [flags] [file ...]")
+		fmt.Fprintln(flag.CommandLine.Output(), "\nConverts logstash pattern files into a Go map or generates a parser for an expression.")
+		flag.PrintDefaults()
+	}
+	packageName := flag.String("pkg", os.Getenv("GOPACKAGE"), "package of the generated file, defaults to $GOPACKAGE set by go generate")
+	variable := flag.String("var", "", "name of the generated map variable")
+	doc := flag.String("doc", "", "doc comment of the map variable")
+	output := flag.String("o", "-", "file to write to, \"-\" for stdout")
+	structs := flag.Bool("structs", false, "generate a struct for every pattern not used by another one")
+	packs := flag.String("packs", "", "comma separated list of bundled packs the patterns refer to, used with -structs and -expression")
+	expression := flag.String("expression", "", "generate a struct and a parser for this grok expression instead of a map")
+	typeName := flag.String("type", "", "name of the struct generated for -expression, derived from the expression by default")
+	flag.Parse()
+
+	var err error
+	if len(*expression) > 0 {
+		err = runParser(*packageName, *expression, *typeName, *output, *packs, flag.Args())
+	} else {
+		err = run(*packageName, *variable, *doc, *output, *structs, *packs, flag.Args())
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "grokgen: %s\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(packageName, variable, doc, output string, withStructs bool, packs string, paths []string) error {
+	if len(variable) == 0 || len(packageName) == 0 || len(paths) == 0 {
+		flag.Usage()
+		return fmt.Errorf("-var, -pkg and at least one file are required")
+	}
+
+	file := codegen.PatternMapFile{
+		Package:  packageName,
+		Variable: variable,
+		Sources:  make([]codegen.Source, 0, len(paths)),
+	}
+	if len(doc) > 0 {
+		file.Doc = " " + doc
+	}
+
+	for _, path := range paths {
+		source, err := readSource(path)
+		if err != nil {
+			return err
+		}
+		file.Sources = append(file.Sources, source)
+	}
+
+	if withStructs {
+		structs, err := topLevelStructs(file.Sources, packs)
+		if err != nil {
+			return err
+		}
+		file.Structs = structs
+	}
+
+	code, err := file.Generate()
+	if err != nil {
+		return err
+	}
+	return write(output, code)
+}
+
+func runParser(packageName, expression, typeName, output, packs string, paths []string) error {
+	if len(packageName) == 0 {
+		flag.Usage()
+		return fmt.Errorf("-pkg is required")
+	}
+
+	sources := make([]codegen.Source, 0, len(paths))
+	for _, path := range paths {
+		source, err := readSource(path)
+		if err != nil {
+			return err
+		}
+		sources = append(sources, source)
+	}
+
+	g, err := newGrok(sources, packs)
+	if err != nil {
+		return err
+	}
+	compiled, err := g.Compile(expression)
+	if err != nil {
+		return err
+	}
+
+	file := codegen.ParserFile{
+		Package: packageName,
+		Structs: []codegen.Struct{codegen.NewStruct(typeName, compiled)},
+	}
+	code, err := file.Generate()
+	if err != nil {
+		return err
+	}
+	return write(output, code)
+}
+
+// write writes the generated code to a file or stdout for "-"
+func write(output string, code []byte) error {
+	if output == "-" {
+		_, err := os.Stdout.Write(code)
+		return err
+	}
+	return os.WriteFile(output, code, 0644)
+}
+
+// newGrok returns a grok instance with the patterns of the given packs and
+// sources, only capturing named fields
+func newGrok(sources []codegen.Source, packs string) (*grok.Grok, error) {
+	defined := make(map[string]string)
+	for _, name := range strings.Split(packs, ",") {
+		if name = strings.TrimSpace(name); len(name) == 0 {
+			continue
+		}
+		pack, known := patterns.Packs[name]
+		if !known {
+			return nil, fmt.Errorf("unknown pattern pack %s", name)
+		}
+		for key, expression := range pack {
+			defined[key] = expression
+		}
+	}
+	for _, source := range sources {
+		for _, line := range source.Lines {
+			if len(line.Name) > 0 {
+				defined[line.Name] = line.Expression
+			}
+		}
+	}
+	return grok.New(grok.Config{NamedCapturesOnly: true, Patterns: defined})
+}
+
+// readSource reads a pattern file
+func readSource(path string) (codegen.Source, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return codegen.Source{}, err
+	}
+	defer file.Close()
+
+	lines, err := grok.ReadPatternLines(file)
+	if err != nil {
+		return codegen.Source{}, fmt.Errorf("%s: %s", path, err)
+	}
+	return codegen.Source{Name: filepath.ToSlash(path), Lines: lines}, nil
+}
+
+// topLevelStructs returns a struct for every pattern of the sources that
+// is not referenced by another pattern of the sources
+func topLevelStructs(sources []codegen.Source, packs string) ([]codegen.Struct, error) {
+	g, err := newGrok(sources, packs)
+	if err != nil {
+		return nil, err
+	}
+
+	names := []string{}
+	for _, source := range sources {
+		for _, line := range source.Lines {
+			if len(line.Name) > 0 {
+				names = append(names, line.Name)
+			}
+		}
+	}
+
+	graph := g.Dependencies()
+	structs := []codegen.Struct{}
+	for _, name := range names {
+		if isReferenced(graph, name, names) {
+			continue
+		}
+		compiled, err := g.Compile("%{" + name + "}")
+		if err != nil {
+			return nil, err
+		}
+		structs = append(structs, codegen.NewStruct("", compiled))
+	}
+	return structs, nil
+}
+
+// isReferenced returns true if one of the given patterns refers to name
+func isReferenced(graph grok.DependencyGraph, name string, names []string) bool {
+	dependents := graph.Dependents(name)
+	for _, other := range names {
+		if i := sort.SearchStrings(dependents, other); i < len(dependents) && dependents[i] == other {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,145 @@
+package codegen
+
+import (
+	"bytes"
+	"go/format"
+	"text/template"
+)
+
+// ParserFile describes a Go file with a struct and a parser for each of a
+// set of grok expressions. The generated parsers are created with the Grok
+// instance holding the patterns at runtime and match lines without
+// reflection by looking up captures by their group index.
+type ParserFile struct {
+	// Package is the name of the package of the generated file
+	Package string
+	// Structs are the generated structs, see NewStruct
+	Structs []Struct
+}
+
+var parserTemplate = template.Must(template.New("parser").Funcs(template.FuncMap{
+	"goString":   goString,
+	"structDecl": structDecl,
+}).Parse(`// Code generated by grokgen. DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+	"fmt"
+	{{- if .NeedsStrconv}}
+	"strconv"
+	{{- end}}
+	"sync"
+
+	"github.com/rtkjweeks/grok-go-pcre"
+)
+{{range .Structs}}{{$type := .Name}}
+{{structDecl .}}
+// {{$type}}Expression is the grok expression {{$type}} is parsed from.
+const {{$type}}Expression = {{goString .Expression}}
+
+// {{$type}}Parser parses lines into {{$type}} values. It is safe for
+// concurrent use.
+type {{$type}}Parser struct {
+	groups   [{{len .Fields}}][]int
+	matchers sync.Pool
+}
+
+// New{{$type}}Parser compiles {{$type}}Expression with the patterns of g.
+func New{{$type}}Parser(g *grok.Grok) (*{{$type}}Parser, error) {
+	compiled, err := g.Compile({{$type}}Expression)
+	if err != nil {
+		return nil, err
+	}
+
+	parser := &{{$type}}Parser{}
+	parser.matchers.New = func() interface{} {
+		return compiled.NewMatcher()
+	}
+	for _, field := range compiled.Fields() {
+		switch field.Name {
+		{{- range $index, $field := .Fields}}
+		case {{printf "%q" $field.Capture}}:
+			parser.groups[{{$index}}] = append(parser.groups[{{$index}}], field.Index)
+		{{- end}}
+		}
+	}
+	for i, name := range []string{ {{- range .Fields}}{{printf "%q" .Capture}}, {{end -}} } {
+		if len(parser.groups[i]) == 0 {
+			return nil, fmt.Errorf("%s is not captured by %s", name, {{$type}}Expression)
+		}
+	}
+	return parser, nil
+}
+
+// Parse matches line and returns the captured fields. Fields that were not
+// captured or are empty keep their zero value. grok.ErrNoMatch is returned
+// if the line does not match.
+func (parser *{{$type}}Parser) Parse(line []byte) ({{$type}}, error) {
+	var result {{$type}}
+	matcher := parser.matchers.Get().(grok.Matcher)
+	defer parser.matchers.Put(matcher)
+
+	if !matcher.Match(line) {
+		return result, grok.ErrNoMatch
+	}
+	{{- range $index, $field := .Fields}}
+	if value := parser.capture(matcher, {{$index}}); len(value) > 0 {
+		{{- if eq $field.Type "int"}}
+		number, err := strconv.Atoi(value)
+		if err != nil {
+			return result, fmt.Errorf("field {{$field.Capture}}: %s", err)
+		}
+		result.{{$field.Name}} = number
+		{{- else if eq $field.Type "float64"}}
+		number, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return result, fmt.Errorf("field {{$field.Capture}}: %s", err)
+		}
+		result.{{$field.Name}} = number
+		{{- else}}
+		result.{{$field.Name}} = value
+		{{- end}}
+	}
+	{{- end}}
+	return result, nil
+}
+
+// capture returns the value of the first group of a field that took part in
+// the last match
+func (parser *{{$type}}Parser) capture(matcher grok.Matcher, field int) string {
+	for _, group := range parser.groups[field] {
+		if matcher.Present(group) {
+			return matcher.GroupString(group)
+		}
+	}
+	return ""
+}
+{{end}}`))
+
+// Generate returns the formatted source code of the file.
+func (file ParserFile) Generate() ([]byte, error) {
+	needsStrconv := false
+	for _, generated := range file.Structs {
+		for _, field := range generated.Fields {
+			needsStrconv = needsStrconv || field.Type != "string"
+		}
+	}
+
+	out := &bytes.Buffer{}
+	err := parserTemplate.Execute(out, struct {
+		ParserFile
+		NeedsStrconv bool
+	}{file, needsStrconv})
+	if err != nil {
+		return nil, err
+	}
+	return format.Source(out.Bytes())
+}
+
+// structDecl returns the declaration of a struct
+func structDecl(generated Struct) string {
+	out := &bytes.Buffer{}
+	generated.write(out)
+	return out.String()
+}
@@ -0,0 +1,70 @@
+package codegen
+
+import (
+	"os"
+	"testing"
+
+	"github.com/rtkjweeks/grok-go-pcre"
+	"github.com/trivago/tgo/ttesting"
+)
+
+//go:generate go run ../cmd/grokgen -pkg codegen -expression "%{IP:client} %{WORD:verb} %{NUMBER:bytes:int} %{NUMBER:took:float}( %{USER:user})?" -type Request -o request_generated_test.go
+
+const requestExpression = `%{IP:client} %{WORD:verb} %{NUMBER:bytes:int} %{NUMBER:took:float}( %{USER:user})?`
+
+func TestParserFile(t *testing.T) {
+	expect := ttesting.NewExpect(t)
+
+	g, err := grok.New(grok.Config{NamedCapturesOnly: true})
+	expect.NoError(err)
+	compiled, err := g.Compile(requestExpression)
+	expect.NoError(err)
+
+	file := ParserFile{Package: "codegen", Structs: []Struct{NewStruct("Request", compiled)}}
+	code, err := file.Generate()
+	expect.NoError(err)
+
+	// The parser tested below must be up to date, run go generate otherwise
+	generated, err := os.ReadFile("request_generated_test.go")
+	expect.NoError(err)
+	expect.Equal(string(generated), string(code))
+}
+
+func TestGeneratedParser(t *testing.T) {
+	expect := ttesting.NewExpect(t)
+
+	g, err := grok.New(grok.Config{})
+	expect.NoError(err)
+	parser, err := NewRequestParser(g)
+	expect.NoError(err)
+
+	request, err := parser.Parse([]byte("10.0.0.1 GET 512 0.25 alice"))
+	expect.NoError(err)
+	expect.Equal(Request{Client: "10.0.0.1", Verb: "GET", Bytes: 512, Took: 0.25, User: "alice"}, request)
+
+	request, err = parser.Parse([]byte("10.0.0.1 GET 512 1"))
+	expect.NoError(err)
+	expect.Equal(Request{Client: "10.0.0.1", Verb: "GET", Bytes: 512, Took: 1}, request)
+
+	_, err = parser.Parse([]byte("10.0.0.1 GET 5.5 1"))
+	expect.NotNil(err)
+	_, err = parser.Parse([]byte("GET"))
+	expect.Equal(grok.ErrNoMatch, err)
+
+	g, err = grok.New(grok.Config{SkipDefaultPatterns: true})
+	expect.NoError(err)
+	_, err = NewRequestParser(g)
+	expect.NotNil(err)
+}
+
+func BenchmarkGeneratedParser(b *testing.B) {
+	g, _ := grok.New(grok.Config{})
+	parser, _ := NewRequestParser(g)
+	line := []byte("10.0.0.1 GET 512 0.25 alice")
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		parser.Parse(line)
+	}
+}
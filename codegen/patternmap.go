@@ -0,0 +1,101 @@
+// Package codegen generates Go source code from grok patterns. It is used
+// by cmd/grokgen to convert logstash pattern files into pattern maps like
+// the ones in the patterns package.
+package codegen
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"strconv"
+	"strings"
+
+	"github.com/rtkjweeks/grok-go-pcre"
+)
+
+// Source is a pattern file read with grok.ReadPatternLines.
+type Source struct {
+	// Name identifies the file in comments, e.g. "patterns/redis"
+	Name  string
+	Lines []grok.PatternLine
+}
+
+// PatternMapFile describes a Go file holding a map of patterns.
+type PatternMapFile struct {
+	// Package is the name of the package of the generated file
+	Package string
+	// Variable is the name of the generated map variable, e.g. "Redis"
+	Variable string
+	// Doc is the doc comment of the variable without the leading "//".
+	// A comment naming the sources is generated if it is empty.
+	Doc string
+	// Sources are written to the map in the given order. Comments and
+	// empty lines are kept, each pattern is followed by a comment naming
+	// the source file and line it was read from.
+	Sources []Source
+	// Structs are appended to the file after the map, see NewStruct
+	Structs []Struct
+}
+
+// Generate returns the formatted source code of the file. An error is
+// returned if a pattern is defined more than once.
+func (file PatternMapFile) Generate() ([]byte, error) {
+	out := &bytes.Buffer{}
+	names := make([]string, 0, len(file.Sources))
+	for _, source := range file.Sources {
+		names = append(names, source.Name)
+	}
+
+	fmt.Fprintf(out, "// Code generated by grokgen from %s. DO NOT EDIT.\n\n", strings.Join(names, ", "))
+	fmt.Fprintf(out, "package %s\n\n", file.Package)
+
+	if len(file.Doc) > 0 {
+		for _, line := range strings.Split(file.Doc, "\n") {
+			fmt.Fprintf(out, "//%s\n", line)
+		}
+	} else {
+		fmt.Fprintf(out, "// %s holds the patterns of %s.\n", file.Variable, strings.Join(names, ", "))
+	}
+	fmt.Fprintf(out, "var %s = map[string]string{\n", file.Variable)
+
+	defined := make(map[string]string)
+	for i, source := range file.Sources {
+		if i > 0 {
+			fmt.Fprintf(out, "\n")
+		}
+		for _, line := range source.Lines {
+			location := fmt.Sprintf("%s:%d", source.Name, line.Number)
+			switch {
+			case line.IsComment:
+				fmt.Fprintf(out, "\t//%s\n", line.Comment)
+
+			case len(line.Name) == 0:
+				fmt.Fprintf(out, "\n")
+
+			default:
+				if previous, duplicate := defined[line.Name]; duplicate {
+					return nil, fmt.Errorf("%s: pattern %s is already defined at %s", location, line.Name, previous)
+				}
+				defined[line.Name] = location
+				fmt.Fprintf(out, "\t%q: %s, // %s\n", line.Name, goString(line.Expression), location)
+			}
+		}
+	}
+	fmt.Fprintf(out, "}\n")
+
+	for _, generated := range file.Structs {
+		fmt.Fprintf(out, "\n")
+		generated.write(out)
+	}
+
+	return format.Source(out.Bytes())
+}
+
+// goString returns a Go string literal for text. Raw string literals are
+// used where possible as patterns are full of backslashes.
+func goString(text string) string {
+	if strings.ContainsAny(text, "`\r") {
+		return strconv.Quote(text)
+	}
+	return "`" + text + "`"
+}
@@ -0,0 +1,62 @@
+package codegen
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/rtkjweeks/grok-go-pcre"
+	"github.com/trivago/tgo/ttesting"
+)
+
+func TestPatternMapFile(t *testing.T) {
+	expect := ttesting.NewExpect(t)
+
+	lines, err := grok.ReadPatternLines(strings.NewReader("# Redis logs\nREDISTIMESTAMP %{MONTHDAY} %{MONTH} %{TIME}\n\nREDISLOG \\[%{POSINT:pid}\\] %{REDISTIMESTAMP:timestamp} `\n"))
+	expect.NoError(err)
+
+	g, err := grok.New(grok.Config{NamedCapturesOnly: true})
+	expect.NoError(err)
+	compiled, err := g.Compile("%{IP:client_ip} %{NUMBER:bytes:int} %{NUMBER:took:float}( %{IP:client_ip})?")
+	expect.NoError(err)
+
+	file := PatternMapFile{
+		Package:  "patterns",
+		Variable: "Redis",
+		Sources:  []Source{{Name: "patterns/redis", Lines: lines}},
+		Structs:  []Struct{NewStruct("Request", compiled)},
+	}
+	code, err := file.Generate()
+	expect.NoError(err)
+	expect.Equal("// Code generated by grokgen from patterns/redis. DO NOT EDIT.\n"+
+		"\n"+
+		"package patterns\n"+
+		"\n"+
+		"// Redis holds the patterns of patterns/redis.\n"+
+		"var Redis = map[string]string{\n"+
+		"\t// Redis logs\n"+
+		"\t\"REDISTIMESTAMP\": `%{MONTHDAY} %{MONTH} %{TIME}`, // patterns/redis:2\n"+
+		"\n"+
+		"\t\"REDISLOG\": \"\\\\[%{POSINT:pid}\\\\] %{REDISTIMESTAMP:timestamp} `\", // patterns/redis:4\n"+
+		"}\n"+
+		"\n"+
+		"// Request holds the fields captured by %{IP:client_ip} %{NUMBER:bytes:int} %{NUMBER:took:float}( %{IP:client_ip})?.\n"+
+		"type Request struct {\n"+
+		"\tClientIp string  `grok:\"client_ip\"`\n"+
+		"\tBytes    int     `grok:\"bytes\"`\n"+
+		"\tTook     float64 `grok:\"took\"`\n"+
+		"}\n", string(code))
+
+	file.Sources = append(file.Sources, file.Sources[0])
+	_, err = file.Generate()
+	expect.NotNil(err)
+}
+
+func TestGoName(t *testing.T) {
+	expect := ttesting.NewExpect(t)
+
+	expect.Equal("Syslog5424Pri", GoName("syslog5424_pri"))
+	expect.Equal("ElbAccessLog", GoName("ELB_ACCESS_LOG"))
+	expect.Equal("ClientIp", GoName("clientIp"))
+	expect.Equal("JunosSrc", GoName("junos-src"))
+	expect.Equal("F1st", GoName("1st"))
+}
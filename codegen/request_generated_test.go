@@ -0,0 +1,111 @@
+// Code generated by grokgen. DO NOT EDIT.
+
+package codegen
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+
+	"github.com/rtkjweeks/grok-go-pcre"
+)
+
+// Request holds the fields captured by %{IP:client} %{WORD:verb} %{NUMBER:bytes:int} %{NUMBER:took:float}( %{USER:user})?.
+type Request struct {
+	Client string  `grok:"client"`
+	Verb   string  `grok:"verb"`
+	Bytes  int     `grok:"bytes"`
+	Took   float64 `grok:"took"`
+	User   string  `grok:"user"`
+}
+
+// RequestExpression is the grok expression Request is parsed from.
+const RequestExpression = `%{IP:client} %{WORD:verb} %{NUMBER:bytes:int} %{NUMBER:took:float}( %{USER:user})?`
+
+// RequestParser parses lines into Request values. It is safe for
+// concurrent use.
+type RequestParser struct {
+	groups   [5][]int
+	matchers sync.Pool
+}
+
+// NewRequestParser compiles RequestExpression with the patterns of g.
+func NewRequestParser(g *grok.Grok) (*RequestParser, error) {
+	compiled, err := g.Compile(RequestExpression)
+	if err != nil {
+		return nil, err
+	}
+
+	parser := &RequestParser{}
+	parser.matchers.New = func() interface{} {
+		return compiled.NewMatcher()
+	}
+	for _, field := range compiled.Fields() {
+		switch field.Name {
+		case "client":
+			parser.groups[0] = append(parser.groups[0], field.Index)
+		case "verb":
+			parser.groups[1] = append(parser.groups[1], field.Index)
+		case "bytes":
+			parser.groups[2] = append(parser.groups[2], field.Index)
+		case "took":
+			parser.groups[3] = append(parser.groups[3], field.Index)
+		case "user":
+			parser.groups[4] = append(parser.groups[4], field.Index)
+		}
+	}
+	for i, name := range []string{"client", "verb", "bytes", "took", "user"} {
+		if len(parser.groups[i]) == 0 {
+			return nil, fmt.Errorf("%s is not captured by %s", name, RequestExpression)
+		}
+	}
+	return parser, nil
+}
+
+// Parse matches line and returns the captured fields. Fields that were not
+// captured or are empty keep their zero value. grok.ErrNoMatch is returned
+// if the line does not match.
+func (parser *RequestParser) Parse(line []byte) (Request, error) {
+	var result Request
+	matcher := parser.matchers.Get().(grok.Matcher)
+	defer parser.matchers.Put(matcher)
+
+	if !matcher.Match(line) {
+		return result, grok.ErrNoMatch
+	}
+	if value := parser.capture(matcher, 0); len(value) > 0 {
+		result.Client = value
+	}
+	if value := parser.capture(matcher, 1); len(value) > 0 {
+		result.Verb = value
+	}
+	if value := parser.capture(matcher, 2); len(value) > 0 {
+		number, err := strconv.Atoi(value)
+		if err != nil {
+			return result, fmt.Errorf("field bytes: %s", err)
+		}
+		result.Bytes = number
+	}
+	if value := parser.capture(matcher, 3); len(value) > 0 {
+		number, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return result, fmt.Errorf("field took: %s", err)
+		}
+		result.Took = number
+	}
+	if value := parser.capture(matcher, 4); len(value) > 0 {
+		result.User = value
+	}
+	return result, nil
+}
+
+// capture returns the value of the first group of a field that took part in
+// the last match
+func (parser *RequestParser) capture(matcher grok.Matcher, field int) string {
+	for _, group := range parser.groups[field] {
+		if matcher.Present(group) {
+			return matcher.GroupString(group)
+		}
+	}
+	return ""
+}
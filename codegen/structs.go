@@ -0,0 +1,112 @@
+package codegen
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+
+	"github.com/rtkjweeks/grok-go-pcre"
+)
+
+// Struct describes a Go struct with one field per named capture of a grok
+// expression.
+type Struct struct {
+	// Name is the name of the Go type
+	Name string
+	// Expression is the grok expression the struct was generated from
+	Expression string
+	Fields     []StructField
+}
+
+// StructField is a field of a generated struct.
+type StructField struct {
+	// Name is the name of the Go field
+	Name string
+	// Capture is the name of the capture in the grok expression
+	Capture string
+	// Type is the Go type of the field, i.e. int, float64 or string
+	Type string
+	// Groups holds the indices of all capture groups with this name
+	Groups []int
+}
+
+// NewStruct returns a struct with a field for each distinct capture name of
+// compiled. Fields are ordered by their first capture group and typed by
+// their type hints, e.g. %{NUMBER:bytes:int}. Pass an empty name to derive
+// it from the expression.
+func NewStruct(name string, compiled *grok.CompiledGrok) Struct {
+	if len(name) == 0 {
+		name = GoName(strings.Trim(compiled.String(), "%{}"))
+	}
+	generated := Struct{Name: name, Expression: compiled.String()}
+
+	byCapture := make(map[string]int)
+	usedNames := make(map[string]bool)
+	for _, field := range compiled.Fields() {
+		if index, known := byCapture[field.Name]; known {
+			generated.Fields[index].Groups = append(generated.Fields[index].Groups, field.Index)
+			continue
+		}
+
+		fieldName := GoName(field.Name)
+		for suffix := 2; usedNames[fieldName]; suffix++ {
+			fieldName = fmt.Sprintf("%s%d", GoName(field.Name), suffix)
+		}
+		usedNames[fieldName] = true
+
+		byCapture[field.Name] = len(generated.Fields)
+		generated.Fields = append(generated.Fields, StructField{
+			Name:    fieldName,
+			Capture: field.Name,
+			Type:    goType(field.Type),
+			Groups:  []int{field.Index},
+		})
+	}
+	return generated
+}
+
+// GoName converts a pattern or capture name into an exported Go
+// identifier, e.g. "syslog5424_pri" to "Syslog5424Pri" and "ELB_ACCESS_LOG"
+// to "ElbAccessLog".
+func GoName(name string) string {
+	var goName strings.Builder
+	for _, part := range strings.FieldsFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		runes := []rune(strings.ToLower(part))
+		if part != strings.ToUpper(part) {
+			// keep camel case names like clientIp
+			runes = []rune(part)
+		}
+		runes[0] = unicode.ToUpper(runes[0])
+		goName.WriteString(string(runes))
+	}
+
+	if goName.Len() == 0 || !unicode.IsLetter([]rune(goName.String())[0]) {
+		return "F" + goName.String()
+	}
+	return goName.String()
+}
+
+// goType returns the Go type for a grok type hint
+func goType(typeHint string) string {
+	switch typeHint {
+	case "int":
+		return "int"
+	case "float":
+		return "float64"
+	default:
+		return "string"
+	}
+}
+
+// write writes the struct declaration
+func (generated Struct) write(out io.Writer) {
+	fmt.Fprintf(out, "// %s holds the fields captured by %s.\n", generated.Name, generated.Expression)
+	fmt.Fprintf(out, "type %s struct {\n", generated.Name)
+	for _, field := range generated.Fields {
+		fmt.Fprintf(out, "\t%s %s `grok:%q`\n", field.Name, field.Type, field.Capture)
+	}
+	fmt.Fprintf(out, "}\n")
+}
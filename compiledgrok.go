@@ -1,18 +1,28 @@
 package grok
 
 import (
+	"errors"
 	"fmt"
 	"strconv"
-	"github.com/rtkjweeks/go-pcre"
+	"time"
 )
 
+// ErrNoMatch is returned by parsers generated with the codegen package if a
+// line does not match the expression.
+var ErrNoMatch = errors.New("grok: line does not match")
+
 // CompiledGrok represents a compiled Grok expression.
 // Use Grok.Compile to generate a CompiledGrok object.
 type CompiledGrok struct {
-	regexp        pcre.Regexp
+	pattern       *grokPattern
+	regexp        Regexp
 	typeHints     typeHintByKey
 	removeEmpty   bool
 	groupIdToName []string
+	// groupIdToPattern holds the pattern each group was captured with
+	groupIdToPattern []string
+	// stats is nil unless Config.CollectStats is set
+	stats *statsCollector
 }
 
 type typeHintByKey map[string]string
@@ -20,38 +30,131 @@ type typeHintByKey map[string]string
 // Match returns true if the given data matches the pattern.
 func (compiled CompiledGrok) Match(data []byte) bool {
 	matcher := compiled.regexp.NewMatcher()
-	return matcher.Match(data, 0)
+	if compiled.stats == nil {
+		return matcher.Match(data)
+	}
+
+	start := time.Now()
+	matched := matcher.Match(data)
+	compiled.stats.record(matcher, matched, time.Since(start), nil)
+	return matched
 }
 
 // MatchString returns true if the given text matches the pattern.
 func (compiled CompiledGrok) MatchString(text string) bool {
-	matcher := compiled.regexp.NewMatcher()
-	return matcher.MatchString(text, 0)
+	return compiled.matchString(compiled.regexp.NewMatcher(), text)
 }
 
+// matchString does the work of MatchString using a caller provided matcher
+func (compiled CompiledGrok) matchString(matcher Matcher, text string) bool {
+	if compiled.stats == nil {
+		return matcher.MatchString(text)
+	}
+
+	start := time.Now()
+	matched := matcher.MatchString(text)
+	compiled.stats.record(matcher, matched, time.Since(start), nil)
+	return matched
+}
+
+
+// NewMatcher returns a matcher for the expanded expression. Captures can be
+// accessed by the group indices returned by Fields.
+func (compiled CompiledGrok) NewMatcher() Matcher {
+	return compiled.regexp.NewMatcher()
+}
 
 // MatchAgainst
 // returns true if the given text matches the pattern.
 //         An object which can be used to extract individual matches by name
 func (compiled CompiledGrok) MatchAgainst(text string) (bool, map[string]string) {
-	matcher := compiled.regexp.NewMatcher()
-	matched :=  matcher.MatchString(text, 0)
+	return compiled.matchAgainst(compiled.regexp.NewMatcher(), text)
+}
+
+// matchAgainst does the work of MatchAgainst using a caller provided matcher,
+// so that callers which match many lines can reuse a single matcher.
+func (compiled CompiledGrok) matchAgainst(matcher Matcher, text string) (bool, map[string]string) {
+	var start time.Time
+	if compiled.stats != nil {
+		start = time.Now()
+	}
+	matched :=  matcher.MatchString(text)
 
 	values := make(map[string]string)
 	if matched {
 		// Now that we've matched, find out which capture groups are present, and map
 		// them back to names in order to provide a key/value map back to the
 		// caller
-		for i := 0; i <= matcher.Groups(); i++ {
+		for i := 0; i <= compiled.regexp.Groups(); i++ {
 			if matcher.Present(i) {
 				values[ compiled.groupIdToName[i] ] = matcher.GroupString(i)
 			}
 		}
 	}
 
+	if compiled.stats != nil {
+		compiled.stats.record(matcher, matched, time.Since(start), values)
+	}
 	return matched, values
 }
 
+// MatchAgainstTyped works like MatchAgainst but casts values based on the
+// type hints of the expression, e.g. %{NUMBER:bytes:int}.
+// Empty values are removed if RemoveEmptyValues was set.
+func (compiled CompiledGrok) MatchAgainstTyped(text string) (bool, map[string]interface{}, error) {
+	matched, values := compiled.MatchAgainst(text)
+	if !matched {
+		return false, map[string]interface{}{}, nil
+	}
+
+	typed, err := compiled.ConvertTypes(values)
+	return err == nil, typed, err
+}
+
+// MatchAgainstNested works like MatchAgainstTyped but returns field paths
+// like %{IP:[client][ip]} or %{IP:client.ip} as nested maps, see Nest.
+func (compiled CompiledGrok) MatchAgainstNested(text string) (bool, map[string]interface{}, error) {
+	matched, values, err := compiled.MatchAgainstTyped(text)
+	if !matched || err != nil {
+		return matched, values, err
+	}
+
+	nested, err := Nest(values)
+	return err == nil, nested, err
+}
+
+// ConvertTypes casts the values returned by MatchAgainst based on the type
+// hints of the expression. Unnamed groups are dropped and empty values are
+// removed if RemoveEmptyValues was set.
+func (compiled CompiledGrok) ConvertTypes(values map[string]string) (map[string]interface{}, error) {
+	typed := make(map[string]interface{}, len(values))
+	for key, match := range values {
+		if compiled.omitStringField(key, match) {
+			continue
+		}
+		value, err := compiled.typeCast(match, key)
+		if err != nil {
+			return nil, err
+		}
+		typed[key] = value
+	}
+	return typed, nil
+}
+
+// FieldNames returns the distinct names of all named captures of the
+// expression in the order they appear in the expression.
+func (compiled CompiledGrok) FieldNames() []string {
+	names := make([]string, 0, len(compiled.groupIdToName))
+	known := make(map[string]bool)
+	for _, name := range compiled.groupIdToName {
+		if len(name) > 0 && !known[name] {
+			known[name] = true
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
 // omitField return true if the field is to be omitted
 func (compiled CompiledGrok) omitField(key string, match []byte) bool {
 	return len(key) == 0 || compiled.removeEmpty && len(match) == 0
@@ -68,7 +171,11 @@ func (compiled CompiledGrok) typeCast(match, key string) (interface{}, error) {
 	if !hasTypeHint {
 		return match, nil
 	}
+	return castValue(match, typeName)
+}
 
+// castValue converts a value to the given type
+func castValue(match, typeName string) (interface{}, error) {
 	switch typeName {
 	case "int":
 		return strconv.Atoi(match)
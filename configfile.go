@@ -0,0 +1,443 @@
+package grok
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// defaultMultilineMaxLines is used if MultilineConfig.MaxLines is not set
+const defaultMultilineMaxLines = 500
+
+// FileConfig is the content of a configuration file read by LoadConfig. It
+// describes the patterns to load and how the events of each source are
+// parsed, so parsing can be changed without recompiling.
+type FileConfig struct {
+	NamedCapturesOnly bool `json:"named_captures_only"`
+	RemoveEmptyValues bool `json:"remove_empty_values"`
+	ECSCompatibility  bool `json:"ecs_compatibility"`
+	// Engine is the name of the regexp engine, see LookupEngine. Defaults
+	// to DefaultEngine if not set.
+	Engine string `json:"engine"`
+	// PatternFiles are pattern files or directories loaded with
+	// LoadPatterns. Their definitions replace the default patterns of the
+	// same name, and later files replace definitions of earlier ones.
+	PatternFiles []string `json:"pattern_files"`
+	// Patterns replace definitions of the default patterns and the pattern
+	// files of the same name
+	Patterns map[string]string `json:"patterns"`
+	// Expressions names grok expressions, so that sources can refer to them
+	// by name
+	Expressions map[string]string `json:"expressions"`
+	// Sources describes the parsing of each source by name
+	Sources map[string]SourceConfig `json:"sources"`
+}
+
+// SourceConfig describes how the events of a source are parsed.
+type SourceConfig struct {
+	// Expressions are tried in the given order. Each is either the name of
+	// an entry of FileConfig.Expressions or a grok expression.
+	Expressions []string `json:"expressions"`
+	// Types maps field names to int, float or string. They replace type
+	// hints of the expressions like %{NUMBER:bytes:int}. Redacted fields
+	// are not converted and returned as strings.
+	Types map[string]string `json:"types"`
+	// Rename maps field names to the names they are returned with. Fields
+	// are renamed after they were converted and redacted.
+	Rename map[string]string `json:"rename"`
+	// Redact lists the redaction rules applied to all expressions
+	Redact []RedactConfig `json:"redact"`
+	// Multiline joins lines to events before they are parsed
+	Multiline *MultilineConfig `json:"multiline"`
+}
+
+// RedactConfig is a RedactRule stored in a configuration file.
+type RedactConfig struct {
+	Fields   []string `json:"fields"`
+	Patterns []string `json:"patterns"`
+	// Action is the name of the action, see ParseRedactAction
+	Action string `json:"action"`
+	// KeyEnv is the name of the environment variable holding the key used
+	// by the hash action, so that keys are not stored with the
+	// configuration.
+	KeyEnv   string `json:"key_env"`
+	Keep     int    `json:"keep"`
+	IPv4Bits int    `json:"ipv4_bits"`
+	IPv6Bits int    `json:"ipv6_bits"`
+}
+
+// MultilineConfig describes which lines are joined to a single event, like
+// the multiline codec of logstash, e.g. the lines of a stack trace.
+type MultilineConfig struct {
+	// Pattern is a grok expression matched against each line
+	Pattern string `json:"pattern"`
+	// Negate joins the lines that do not match Pattern instead of the ones
+	// that match
+	Negate bool `json:"negate"`
+	// What is "previous" if a joined line belongs to the line before it,
+	// or "next" if it belongs to the line after it
+	What string `json:"what"`
+	// MaxLines limits the number of lines of an event. Defaults to 500 if
+	// not set.
+	MaxLines int `json:"max_lines"`
+}
+
+// Parsers holds a Grok and the parser of each source described by a
+// FileConfig.
+type Parsers struct {
+	Grok    *Grok
+	sources map[string]*SourceParser
+}
+
+// SourceParser parses the events of a single source. It is safe for
+// concurrent use.
+type SourceParser struct {
+	name      string
+	set       *PatternSet
+	redactors []*Redactor
+	types     map[string]string
+	rename    map[string]string
+	multiline *multilineRule
+}
+
+// Multiline joins the lines of a source to events, see MultilineConfig.
+// A Multiline is not safe for concurrent use.
+type Multiline struct {
+	rule    *multilineRule
+	pending []string
+}
+
+type multilineRule struct {
+	compiled *CompiledGrok
+	negate   bool
+	previous bool
+	maxLines int
+}
+
+// LoadConfig reads a configuration file stored as JSON, see FileConfig, and
+// builds the parsers it describes. Relative pattern files are looked up
+// relative to the directory of the configuration file. YAML files are read
+// by package yamlconfig, so that only its users depend on a YAML decoder.
+func LoadConfig(path string) (*Parsers, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return nil, fmt.Errorf("%s: YAML configurations are read by package yamlconfig", path)
+	}
+	return LoadConfigWith(path, ReadConfig)
+}
+
+// LoadConfigWith works like LoadConfig but decodes the configuration file
+// with read, e.g. yamlconfig.Read.
+func LoadConfigWith(path string, read func(r io.Reader) (*FileConfig, error)) (*Parsers, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	config, err := read(file)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", path, err)
+	}
+	for i, patternFile := range config.PatternFiles {
+		if !filepath.IsAbs(patternFile) {
+			config.PatternFiles[i] = filepath.Join(filepath.Dir(path), patternFile)
+		}
+	}
+
+	parsers, err := config.NewParsers()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", path, err)
+	}
+	return parsers, nil
+}
+
+// ReadConfig reads a configuration stored as JSON. Unknown keys are
+// rejected, so that typos do not go unnoticed.
+func ReadConfig(r io.Reader) (*FileConfig, error) {
+	config := &FileConfig{}
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(config); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// NewParsers loads the patterns and compiles the expressions of all
+// sources. Relative pattern files are looked up relative to the working
+// directory.
+func (config FileConfig) NewParsers() (*Parsers, error) {
+	grokConfig := Config{
+		NamedCapturesOnly: config.NamedCapturesOnly,
+		RemoveEmptyValues: config.RemoveEmptyValues,
+		ECSCompatibility:  config.ECSCompatibility,
+	}
+	if len(config.Engine) > 0 {
+		engine, err := LookupEngine(config.Engine)
+		if err != nil {
+			return nil, err
+		}
+		grokConfig.Engine = engine
+	}
+
+	definitions := make(map[string]string)
+	for _, path := range config.PatternFiles {
+		patterns, err := LoadPatterns(path)
+		if err != nil {
+			return nil, err
+		}
+		for name, expression := range patterns {
+			definitions[name] = expression
+		}
+	}
+	for name, expression := range config.Patterns {
+		definitions[name] = expression
+	}
+
+	grok, err := New(grokConfig.withOverrides(definitions))
+	if err != nil {
+		return nil, err
+	}
+	parsers := &Parsers{
+		Grok:    grok,
+		sources: make(map[string]*SourceParser, len(config.Sources)),
+	}
+	for name, source := range config.Sources {
+		parser, err := grok.newSourceParser(name, source, config.Expressions)
+		if err != nil {
+			return nil, fmt.Errorf("source %s: %s", name, err)
+		}
+		parsers.sources[name] = parser
+	}
+	return parsers, nil
+}
+
+// newSourceParser compiles the expressions and rules of a source
+func (grok Grok) newSourceParser(name string, config SourceConfig, named map[string]string) (*SourceParser, error) {
+	if len(config.Expressions) == 0 {
+		return nil, errors.New("no expressions given")
+	}
+	parser := &SourceParser{
+		name:   name,
+		set:    &PatternSet{},
+		types:  config.Types,
+		rename: config.Rename,
+	}
+	for field, typeName := range config.Types {
+		if typeName != "int" && typeName != "float" && typeName != "string" {
+			return nil, fmt.Errorf("field %s: unknown type %s, use int, float or string", field, typeName)
+		}
+	}
+
+	rules := make([]RedactRule, 0, len(config.Redact))
+	for _, redact := range config.Redact {
+		action, err := ParseRedactAction(redact.Action)
+		if err != nil {
+			return nil, err
+		}
+		rule := RedactRule{
+			Fields:   redact.Fields,
+			Patterns: redact.Patterns,
+			Action:   action,
+			Keep:     redact.Keep,
+			IPv4Bits: redact.IPv4Bits,
+			IPv6Bits: redact.IPv6Bits,
+		}
+		if len(redact.KeyEnv) > 0 {
+			rule.Key = []byte(os.Getenv(redact.KeyEnv))
+		}
+		rules = append(rules, rule)
+	}
+
+	// Rules must select a capture in at least one of the expressions
+	selected := make([]bool, len(rules))
+	for _, expression := range config.Expressions {
+		expressionName := expression
+		if namedExpression, isNamed := named[expression]; isNamed {
+			expression = namedExpression
+		}
+		compiled, err := grok.Compile(expression)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", expressionName, err)
+		}
+		parser.set.Add(expressionName, compiled)
+
+		var redactor *Redactor
+		if len(rules) > 0 {
+			var selects []bool
+			if redactor, selects, err = compiled.newRedactor(rules); err != nil {
+				return nil, fmt.Errorf("%s: %s", expressionName, err)
+			}
+			for i := range selects {
+				selected[i] = selected[i] || selects[i]
+			}
+		}
+		parser.redactors = append(parser.redactors, redactor)
+	}
+	for i, rule := range rules {
+		if !selected[i] {
+			return nil, fmt.Errorf("redact rule for %s selects no capture", rule.names())
+		}
+	}
+
+	if config.Multiline != nil {
+		rule, err := grok.newMultilineRule(*config.Multiline)
+		if err != nil {
+			return nil, fmt.Errorf("multiline: %s", err)
+		}
+		parser.multiline = rule
+	}
+	return parser, nil
+}
+
+// newMultilineRule compiles the pattern of a multiline configuration
+func (grok Grok) newMultilineRule(config MultilineConfig) (*multilineRule, error) {
+	if config.What != "previous" && config.What != "next" {
+		return nil, fmt.Errorf("what must be previous or next, not %q", config.What)
+	}
+	compiled, err := grok.Compile(config.Pattern)
+	if err != nil {
+		return nil, err
+	}
+	return &multilineRule{
+		compiled: compiled,
+		negate:   config.Negate,
+		previous: config.What == "previous",
+		maxLines: defaultInt(config.MaxLines, defaultMultilineMaxLines),
+	}, nil
+}
+
+// Source returns the parser of the given source or nil if there is no such
+// source.
+func (parsers *Parsers) Source(name string) *SourceParser {
+	return parsers.sources[name]
+}
+
+// SourceNames returns the sorted names of all sources.
+func (parsers *Parsers) SourceNames() []string {
+	names := make([]string, 0, len(parsers.sources))
+	for name := range parsers.sources {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Name returns the name of the source.
+func (parser *SourceParser) Name() string {
+	return parser.name
+}
+
+// PatternSet returns the expressions of the source, e.g. to read their
+// statistics. Expressions referred to by name are named after the entry of
+// FileConfig.Expressions.
+func (parser *SourceParser) PatternSet() *PatternSet {
+	return parser.set
+}
+
+// Parse matches an event against the expressions of the source. The fields
+// of the first matching expression are returned converted, redacted and
+// renamed, together with the name of the expression.
+func (parser *SourceParser) Parse(event string) (bool, map[string]interface{}, string, error) {
+	for i, compiled := range parser.set.compiled {
+		var matched bool
+		var values map[string]string
+		redactor := parser.redactors[i]
+		if redactor != nil {
+			matched, values, _ = redactor.MatchAgainst(event)
+		} else {
+			matched, values = compiled.MatchAgainst(event)
+		}
+		if !matched {
+			continue
+		}
+
+		// Redacted values, e.g. a masked number, cannot be converted and are
+		// kept as strings
+		name := parser.set.names[i]
+		converted := make(map[string]string, len(values))
+		for field, value := range values {
+			if !redactor.redacts(field) {
+				converted[field] = value
+			}
+		}
+		typed, err := compiled.ConvertTypes(converted)
+		if err != nil {
+			return false, map[string]interface{}{}, name, err
+		}
+		for field, typeName := range parser.types {
+			if _, isSet := typed[field]; !isSet {
+				continue
+			}
+			if typed[field], err = castValue(values[field], typeName); err != nil {
+				return false, map[string]interface{}{}, name, err
+			}
+		}
+		for field, value := range values {
+			if redactor.redacts(field) && !compiled.omitStringField(field, value) {
+				typed[field] = value
+			}
+		}
+		renamed := make(map[string]interface{}, len(parser.rename))
+		for from, to := range parser.rename {
+			if value, isSet := typed[from]; isSet {
+				delete(typed, from)
+				renamed[to] = value
+			}
+		}
+		for field, value := range renamed {
+			typed[field] = value
+		}
+		return true, typed, name, nil
+	}
+	return false, map[string]interface{}{}, "", nil
+}
+
+// NewMultiline returns a Multiline joining lines as configured for the
+// source. Without multiline configuration, each line is an event.
+func (parser *SourceParser) NewMultiline() *Multiline {
+	return &Multiline{rule: parser.multiline}
+}
+
+// Add adds a line and returns the events it completed. Lines are joined
+// with "\n". As the last event is only completed by the next line or by
+// Flush, callers should flush after a period without new lines.
+func (multiline *Multiline) Add(line string) []string {
+	rule := multiline.rule
+	if rule == nil {
+		return []string{line}
+	}
+
+	events := []string{}
+	if len(multiline.pending) >= rule.maxLines {
+		events = append(events, multiline.Flush()...)
+	}
+
+	// A line that is not joined starts a new event if joined lines belong
+	// to the previous line and ends the event otherwise
+	joined := rule.compiled.MatchString(line) != rule.negate
+	if rule.previous && !joined {
+		events = append(events, multiline.Flush()...)
+	}
+	multiline.pending = append(multiline.pending, line)
+	if !rule.previous && !joined {
+		events = append(events, multiline.Flush()...)
+	}
+	return events
+}
+
+// Flush returns the pending lines as an event, if there are any.
+func (multiline *Multiline) Flush() []string {
+	if len(multiline.pending) == 0 {
+		return nil
+	}
+	event := strings.Join(multiline.pending, "\n")
+	multiline.pending = multiline.pending[:0]
+	return []string{event}
+}
@@ -0,0 +1,179 @@
+package grok
+
+import (
+	"github.com/trivago/tgo/ttesting"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testConfig = `{
+	"named_captures_only": true,
+	"engine": "re2",
+	"pattern_files": ["patterns"],
+	"patterns": {"APPLEVEL": "INFO|WARN|ERROR"},
+	"expressions": {
+		"access": "%{IPORHOST:client} %{WORD:verb} %{NOTSPACE:path} %{NUMBER:status} %{NUMBER:duration:float}",
+		"app": "(?s)%{TIMESTAMP_ISO8601:timestamp} %{APPLEVEL:level} \\[%{APPUSER:user}\\] %{GREEDYDATA:message}"
+	},
+	"sources": {
+		"web": {
+			"expressions": ["access", "%{IPORHOST:client} - %{GREEDYDATA:message}"],
+			"types": {"status": "int", "duration": "string"},
+			"rename": {"client": "source.ip"},
+			"redact": [{"fields": ["client"], "action": "ipprefix"}]
+		},
+		"app": {
+			"expressions": ["app"],
+			"redact": [{"fields": ["user"], "action": "hash", "key_env": "GROK_TEST_KEY"}],
+			"multiline": {"pattern": "^%{TIMESTAMP_ISO8601} ", "negate": true, "what": "previous"}
+		}
+	}
+}`
+
+// writeConfig writes a configuration and a pattern file to a temporary
+// directory and returns the path of the configuration
+func writeConfig(t *testing.T, config string) string {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "patterns"), []byte("APPUSER [a-z]+\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "grok.json")
+	if err := os.WriteFile(path, []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadConfig(t *testing.T) {
+	expect := ttesting.NewExpect(t)
+	t.Setenv("GROK_TEST_KEY", "secret")
+
+	parsers, err := LoadConfig(writeConfig(t, testConfig))
+	expect.NoError(err)
+	expect.Equal([]string{"app", "web"}, parsers.SourceNames())
+	expect.Nil(parsers.Source("db"))
+	expect.Equal("re2", parsers.Grok.Engine().Name())
+
+	web := parsers.Source("web")
+	expect.Equal("web", web.Name())
+	expect.Equal([]string{"access", "%{IPORHOST:client} - %{GREEDYDATA:message}"}, web.PatternSet().Names())
+
+	matched, fields, name, err := web.Parse("10.1.2.3 GET /index.html 200 0.25")
+	expect.NoError(err)
+	expect.True(matched)
+	expect.Equal("access", name)
+	expect.Equal(map[string]interface{}{
+		"source.ip": "10.1.2.0",
+		"verb":      "GET",
+		"path":      "/index.html",
+		"status":    200,
+		"duration":  "0.25",
+	}, fields)
+
+	matched, fields, name, err = web.Parse("example.com - hello")
+	expect.NoError(err)
+	expect.True(matched)
+	expect.Equal("%{IPORHOST:client} - %{GREEDYDATA:message}", name)
+	expect.Equal("hello", fields["message"])
+
+	matched, _, _, err = web.Parse("-")
+	expect.NoError(err)
+	expect.False(matched)
+
+	app := parsers.Source("app")
+	matched, fields, _, err = app.Parse("2026-10-19T10:00:00Z ERROR [amy] failed\n  at main")
+	expect.NoError(err)
+	expect.True(matched)
+	expect.Equal("failed\n  at main", fields["message"])
+	expect.Equal(64, len(fields["user"].(string)))
+}
+
+func TestMultiline(t *testing.T) {
+	expect := ttesting.NewExpect(t)
+	t.Setenv("GROK_TEST_KEY", "secret")
+	parsers, err := LoadConfig(writeConfig(t, testConfig))
+	expect.NoError(err)
+
+	lines := []string{
+		"2026-10-19T10:00:00Z ERROR [amy] failed",
+		"  at main",
+		"  at init",
+		"2026-10-19T10:00:01Z INFO [bob] ok",
+	}
+	multiline := parsers.Source("app").NewMultiline()
+	events := []string{}
+	for _, line := range lines {
+		events = append(events, multiline.Add(line)...)
+	}
+	expect.Equal([]string{strings.Join(lines[:3], "\n")}, events)
+	expect.Equal([]string{lines[3]}, multiline.Flush())
+	expect.Equal(0, len(multiline.Flush()))
+
+	// Lines ending with a backslash continue on the next line
+	g, _ := New(Config{})
+	rule, err := g.newMultilineRule(MultilineConfig{Pattern: `\\$`, What: "next", MaxLines: 2})
+	expect.NoError(err)
+	multiline = &Multiline{rule: rule}
+	expect.Equal(0, len(multiline.Add(`a \`)))
+	expect.Equal([]string{"a \\\nb"}, multiline.Add("b"))
+	expect.Equal(0, len(multiline.Add(`c \`)))
+	expect.Equal(0, len(multiline.Add(`d \`)))
+	expect.Equal([]string{"c \\\nd \\"}, multiline.Add(`e \`))
+
+	// Without configuration each line is an event
+	multiline = parsers.Source("web").NewMultiline()
+	expect.Equal([]string{"x"}, multiline.Add("x"))
+	expect.Equal(0, len(multiline.Flush()))
+}
+
+func TestRedactTypedFields(t *testing.T) {
+	expect := ttesting.NewExpect(t)
+
+	parsers, err := LoadConfig(writeConfig(t, `{
+		"sources": {
+			"web": {
+				"expressions": ["%{NUMBER:bytes:int} %{NUMBER:duration} %{NUMBER:status:int}"],
+				"types": {"duration": "float"},
+				"redact": [{"fields": ["bytes", "duration"], "action": "mask"}]
+			}
+		}
+	}`))
+	expect.NoError(err)
+
+	// Redacted fields are not converted
+	matched, fields, _, err := parsers.Source("web").Parse("512 0.25 200")
+	expect.NoError(err)
+	expect.True(matched)
+	expect.Equal("***", fields["bytes"])
+	expect.Equal("****", fields["duration"])
+	expect.Equal(200, fields["status"])
+}
+
+func TestLoadConfigErrors(t *testing.T) {
+	expect := ttesting.NewExpect(t)
+
+	for _, config := range []string{
+		`{"sources": {"web": {"expressions": ["%{UNKNOWN}"]}}}`,
+		`{"sources": {"web": {"expressions": []}}}`,
+		`{"sources": {"web": {"expressions": ["%{WORD}"], "types": {"x": "bool"}}}}`,
+		`{"sources": {"web": {"expressions": ["%{WORD}"], "redact": [{"fields": ["x"], "action": "shred"}]}}}`,
+		`{"sources": {"web": {"expressions": ["%{WORD}"], "redact": [{"fields": ["x"], "action": "hash", "key_env": "GROK_UNSET_KEY"}]}}}`,
+		`{"sources": {"web": {"expressions": ["%{WORD}"], "redact": [{"fields": ["x"], "action": "mask"}]}}}`,
+		`{"sources": {"web": {"expressions": ["%{WORD}"], "multiline": {"pattern": "^ ", "what": "before"}}}}`,
+		`{"engine": "unknown"}`,
+		`{"pattern_files": ["missing"]}`,
+		`{"sourcez": {}}`,
+	} {
+		_, err := LoadConfig(writeConfig(t, config))
+		expect.NotNil(err)
+	}
+
+	// YAML files are read by package yamlconfig
+	path := writeConfig(t, "sources: {}\n")
+	yamlPath := strings.TrimSuffix(path, ".json") + ".yaml"
+	expect.NoError(os.Rename(path, yamlPath))
+	_, err := LoadConfig(yamlPath)
+	expect.NotNil(err)
+}
@@ -0,0 +1,127 @@
+package grok
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Sample is a line of text together with the fields a pattern is expected
+// to capture from it. Pattern is either the name of a known pattern or a
+// grok expression. If NoMatch is set, the pattern must not match Input.
+type Sample struct {
+	Pattern string            `json:"pattern"`
+	Input   string            `json:"input"`
+	Fields  map[string]string `json:"fields,omitempty"`
+	NoMatch bool              `json:"nomatch,omitempty"`
+}
+
+// Corpus is a set of samples used to verify patterns.
+type Corpus []Sample
+
+// VerifyError describes a sample that did not produce the expected result.
+type VerifyError struct {
+	Sample  Sample
+	Message string
+	// Err is the error returned by Compile if the pattern did not compile
+	Err error
+}
+
+// Error returns a description containing the pattern, input and problem.
+func (err VerifyError) Error() string {
+	return fmt.Sprintf("%s: %q: %s", err.Sample.Pattern, err.Sample.Input, err.Message)
+}
+
+// ReadCorpus reads a corpus stored as a JSON array of samples, e.g.
+// [{"pattern": "HOSTPORT", "input": "example.com:80", "fields": {"HOSTNAME": "example.com"}}]
+func ReadCorpus(r io.Reader) (Corpus, error) {
+	corpus := Corpus{}
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&corpus); err != nil {
+		return nil, err
+	}
+	return corpus, nil
+}
+
+// Patterns returns the sorted names of all patterns used by the corpus.
+func (corpus Corpus) Patterns() []string {
+	known := make(map[string]bool)
+	names := []string{}
+	for _, sample := range corpus {
+		if !known[sample.Pattern] {
+			known[sample.Pattern] = true
+			names = append(names, sample.Pattern)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Inputs returns the input lines of all samples in corpus order.
+func (corpus Corpus) Inputs() []string {
+	inputs := make([]string, 0, len(corpus))
+	for _, sample := range corpus {
+		inputs = append(inputs, sample.Input)
+	}
+	return inputs
+}
+
+// Verify matches all samples of the corpus with the given grok instance.
+// Each expected field must be captured with exactly the given value, an
+// expected empty value also accepts a field that was not captured. Fields
+// not listed in a sample are not checked.
+// A VerifyError is returned for each sample that does not behave as
+// expected, so an empty list means the whole corpus passed.
+func Verify(grok *Grok, corpus Corpus) []VerifyError {
+	errors := []VerifyError{}
+	compiled := make(map[string]*CompiledGrok)
+
+	for _, sample := range corpus {
+		expression := sample.Pattern
+		if !strings.Contains(expression, "%{") {
+			expression = "%{" + expression + "}"
+		}
+
+		c, isCompiled := compiled[expression]
+		if !isCompiled {
+			var err error
+			if c, err = grok.Compile(expression); err != nil {
+				errors = append(errors, VerifyError{sample, err.Error(), err})
+				continue
+			}
+			compiled[expression] = c
+		}
+
+		matched, values := c.MatchAgainst(sample.Input)
+		switch {
+		case sample.NoMatch && matched:
+			errors = append(errors, VerifyError{sample, "matched but was expected not to match", nil})
+			continue
+		case sample.NoMatch:
+			continue
+		case !matched:
+			errors = append(errors, VerifyError{sample, "did not match", nil})
+			continue
+		}
+
+		keys := make([]string, 0, len(sample.Fields))
+		for key := range sample.Fields {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			expected := sample.Fields[key]
+			actual, isSet := values[key]
+			if !isSet && len(expected) > 0 {
+				errors = append(errors, VerifyError{sample, fmt.Sprintf("field %s was not captured, expected %q", key, expected), nil})
+			} else if actual != expected {
+				errors = append(errors, VerifyError{sample, fmt.Sprintf("field %s is %q, expected %q", key, actual, expected), nil})
+			}
+		}
+	}
+	return errors
+}
@@ -0,0 +1,41 @@
+package grok
+
+import (
+	"github.com/trivago/tgo/ttesting"
+	"strings"
+	"testing"
+)
+
+func TestVerify(t *testing.T) {
+	expect := ttesting.NewExpect(t)
+
+	corpus, err := ReadCorpus(strings.NewReader(`[
+		{"pattern": "HOSTPORT", "input": "example.com:80"},
+		{"pattern": "%{IP:client} %{WORD:verb}", "input": "10.0.0.1 GET", "fields": {"client": "10.0.0.1", "verb": "GET"}},
+		{"pattern": "%{IP:client}( %{WORD:verb})?", "input": "10.0.0.1", "fields": {"verb": ""}},
+		{"pattern": "%{IP:client} %{WORD:verb}", "input": "10.0.0.1 GET", "fields": {"verb": "POST"}},
+		{"pattern": "%{IP:client} %{WORD:verb}", "input": "10.0.0.1 GET", "fields": {"user": "alice"}},
+		{"pattern": "IP", "input": "no address", "nomatch": true},
+		{"pattern": "IP", "input": "10.0.0.1", "nomatch": true},
+		{"pattern": "IP", "input": "no address"},
+		{"pattern": "UNKNOWN", "input": "foo"}
+	]`))
+	expect.NoError(err)
+	expect.Equal([]string{"%{IP:client} %{WORD:verb}", "%{IP:client}( %{WORD:verb})?", "HOSTPORT", "IP", "UNKNOWN"}, corpus.Patterns())
+
+	g, err := New(Config{NamedCapturesOnly: true})
+	expect.NoError(err)
+
+	failures := Verify(g, corpus)
+	expect.Equal(5, len(failures))
+	if len(failures) == 5 {
+		expect.Equal(`field verb is "GET", expected "POST"`, failures[0].Message)
+		expect.Equal(`field user was not captured, expected "alice"`, failures[1].Message)
+		expect.Equal("matched but was expected not to match", failures[2].Message)
+		expect.Equal("did not match", failures[3].Message)
+		expect.Equal("UNKNOWN", failures[4].Sample.Pattern)
+	}
+
+	_, err = ReadCorpus(strings.NewReader(`[{"pattern": "IP", "text": "10.0.0.1"}]`))
+	expect.NotNil(err)
+}
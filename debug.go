@@ -0,0 +1,286 @@
+package grok
+
+import (
+	"regexp"
+	"strings"
+)
+
+// DebugNode describes a %{...} reference of a grok expression and the
+// references used by its definition.
+type DebugNode struct {
+	Reference  string
+	Name       string
+	Alias      string
+	Type       string
+	Definition string
+	Children   []*DebugNode
+}
+
+// DebugFailure describes where a text stopped matching a grok expression.
+// The expression is cut into components (references, groups, character
+// classes and literals) and MatchedPrefix holds the longest run of
+// components that still matches. Component is the first component that
+// does not match anymore and Offset is the position in the text where the
+// matched prefix ended. Path lists the references that were expanded to
+// narrow down the failing component, outermost first.
+type DebugFailure struct {
+	Path          []string
+	MatchedPrefix string
+	Component     string
+	Offset        int
+}
+
+// DebugResult holds the outcome of Grok.Debug.
+type DebugResult struct {
+	Expression string
+	Expanded   string
+	Tree       []*DebugNode
+	Matched    bool
+	Failure    *DebugFailure
+}
+
+var quantifier = regexp.MustCompile(`^(?:[*+?]|\{\d+(?:,\d*)?\})[?+]?`)
+
+// Debug expands a grok expression and matches it against the given text.
+// If the text does not match, the result describes which part of the
+// expression failed. This function is expensive as it compiles many partial
+// expressions and is meant to be used for troubleshooting only.
+func (grok Grok) Debug(expression, text string) (*DebugResult, error) {
+	pattern, err := newPattern(expression, grok.patterns, grok.namedOnly)
+	if err != nil {
+		return nil, err
+	}
+	compiled, err := grok.Compile(expression)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &DebugResult{
+		Expression: expression,
+		Expanded:   pattern.expression,
+		Tree:       grok.expansionTree(expression),
+		Matched:    compiled.MatchString(text),
+	}
+	if !result.Matched {
+		result.Failure = grok.findFailure("", expression, text, []string{})
+	}
+	return result, nil
+}
+
+// expansionTree returns the references of an expression and, recursively,
+// the references of their definitions.
+func (grok Grok) expansionTree(expression string) []*DebugNode {
+	matches := findReferences(namedReference, expression)
+	nodes := make([]*DebugNode, 0, len(matches))
+
+	for _, match := range matches {
+		names := strings.Split(match.NameAndAlias, ":")
+		node := &DebugNode{
+			Reference: match.FullTag,
+			Name:      names[0],
+		}
+		if len(names) > 1 {
+			node.Alias = names[1]
+		}
+		if len(names) > 2 {
+			node.Type = names[2]
+		}
+		if pattern, known := grok.patterns[node.Name]; known {
+			node.Definition = pattern.definition
+			node.Children = grok.expansionTree(pattern.definition)
+		}
+		nodes = append(nodes, node)
+	}
+	return nodes
+}
+
+// findFailure locates the first failing component of expression, assuming
+// that prefix matches the text. Each top level alternative is tried and the
+// one matching the most text is reported.
+func (grok Grok) findFailure(prefix, expression, text string, path []string) *DebugFailure {
+	var best *DebugFailure
+	for _, components := range splitAlternatives(splitComponents(expression)) {
+		failure := grok.findFailingComponent(prefix, components, text, path)
+		if best == nil || failure.Offset > best.Offset {
+			best = failure
+		}
+	}
+	return best
+}
+
+// findFailingComponent extends prefix component by component until the
+// text does not match anymore. If the failing component is a reference or
+// a group, its contents are searched for the failing component, too.
+func (grok Grok) findFailingComponent(prefix string, components []string, text string, path []string) *DebugFailure {
+	offset, _ := grok.matchEnd(prefix, text)
+	matched := prefix
+
+	k := 0
+	for ; k < len(components); k++ {
+		end, isMatch := grok.matchEnd(matched+components[k], text)
+		if !isMatch {
+			break
+		}
+		matched += components[k]
+		offset = end
+	}
+
+	failure := &DebugFailure{
+		Path:          path,
+		MatchedPrefix: matched,
+		Offset:        offset,
+	}
+	if k == len(components) {
+		return failure
+	}
+	failure.Component = components[k]
+
+	// Narrow down the failure by looking into the failing component
+	if inner := grok.innerExpression(failure.Component); len(inner) > 0 {
+		innerPath := append(append([]string{}, path...), failure.Component)
+		if innerFailure := grok.findFailure(matched, inner, text, innerPath); len(innerFailure.Component) > 0 {
+			return innerFailure
+		}
+	}
+	return failure
+}
+
+// innerExpression returns the contents of a reference or group component
+// or an empty string if it cannot be narrowed down. References to patterns
+// without references themselves, e.g. %{POSINT}, are not expanded as they
+// are the most specific answer already. Quantified groups are not expanded
+// either as their contents do not match the same text on their own.
+func (grok Grok) innerExpression(component string) string {
+	var inner string
+	switch {
+	case strings.HasPrefix(component, "%{"):
+		name := strings.Split(component[2:len(component)-1], ":")[0]
+		if pattern, known := grok.patterns[name]; known && strings.HasSuffix(component, "}") &&
+			strings.Contains(pattern.definition, "%{") {
+			inner = pattern.definition
+		}
+	case strings.HasPrefix(component, "(") && strings.HasSuffix(component, ")"):
+		inner = component[1 : len(component)-1]
+		if strings.HasPrefix(inner, "?:") {
+			inner = inner[2:]
+		} else if strings.HasPrefix(inner, "?") {
+			inner = "" // lookarounds, atomic groups, named groups, ...
+		}
+	}
+
+	if inner == component {
+		return ""
+	}
+	return inner
+}
+
+// matchEnd returns the end offset of the first match of expression in text.
+// Expressions that do not compile are reported as not matching.
+func (grok Grok) matchEnd(expression, text string) (int, bool) {
+	if len(expression) == 0 {
+		return 0, true
+	}
+	compiled, err := grok.Compile(expression)
+	if err != nil {
+		return 0, false
+	}
+	matcher := compiled.regexp.NewMatcher()
+	if !matcher.MatchString(text) {
+		return 0, false
+	}
+	return matcher.GroupIndices(0)[1], true
+}
+
+// splitComponents cuts an expression into references, groups, character
+// classes, escape sequences and single characters. Quantifiers are kept
+// with the component they belong to.
+func splitComponents(expression string) []string {
+	components := []string{}
+	for i := 0; i < len(expression); {
+		end := componentEnd(expression, i)
+		if loc := quantifier.FindStringIndex(expression[end:]); loc != nil {
+			end += loc[1]
+		}
+		components = append(components, expression[i:end])
+		i = end
+	}
+	return components
+}
+
+// componentEnd returns the end of the component starting at start
+func componentEnd(expression string, start int) int {
+	switch {
+	case strings.HasPrefix(expression[start:], "%{"):
+		if end := strings.IndexByte(expression[start:], '}'); end > 0 {
+			return start + end + 1
+		}
+
+	case expression[start] == '\\':
+		return minInt(start+2, len(expression))
+
+	case expression[start] == '[':
+		return classEnd(expression, start)
+
+	case expression[start] == '(':
+		depth := 0
+		for i := start; i < len(expression); i++ {
+			switch expression[i] {
+			case '\\':
+				i++
+			case '[':
+				i = classEnd(expression, i) - 1
+			case '(':
+				depth++
+			case ')':
+				depth--
+				if depth == 0 {
+					return i + 1
+				}
+			}
+		}
+		return len(expression)
+	}
+	return start + 1
+}
+
+// classEnd returns the end of the character class starting at start
+func classEnd(expression string, start int) int {
+	i := start + 1
+	if i < len(expression) && expression[i] == '^' {
+		i++
+	}
+	if i < len(expression) && expression[i] == ']' {
+		i++
+	}
+	for ; i < len(expression); i++ {
+		switch expression[i] {
+		case '\\':
+			i++
+		case ']':
+			return i + 1
+		}
+	}
+	return len(expression)
+}
+
+// splitAlternatives splits components at top level "|" components
+func splitAlternatives(components []string) [][]string {
+	alternatives := [][]string{}
+	current := []string{}
+	for _, component := range components {
+		if component == "|" {
+			alternatives = append(alternatives, current)
+			current = []string{}
+			continue
+		}
+		current = append(current, component)
+	}
+	return append(alternatives, current)
+}
+
+func minInt(x, y int) int {
+	if x < y {
+		return x
+	}
+	return y
+}
@@ -0,0 +1,36 @@
+package grok
+
+import (
+	"github.com/trivago/tgo/ttesting"
+	"testing"
+)
+
+func TestDebug(t *testing.T) {
+	expect := ttesting.NewExpect(t)
+
+	g, err := New(Config{NamedCapturesOnly: true})
+	expect.NoError(err)
+
+	result, err := g.Debug("%{HOSTPORT:host} %{WORD:verb}", "example.com:8080 GET")
+	expect.NoError(err)
+	expect.True(result.Matched)
+	expect.Nil(result.Failure)
+	expect.Equal(2, len(result.Tree))
+	expect.Equal("HOSTPORT", result.Tree[0].Name)
+	expect.Equal("host", result.Tree[0].Alias)
+	expect.Equal(2, len(result.Tree[0].Children))
+	expect.Equal("%{IPORHOST}", result.Tree[0].Children[0].Reference)
+
+	result, err = g.Debug("%{WORD:verb} \\[pid %{POSINT:pid}\\]", "GET [pid abc]")
+	expect.NoError(err)
+	expect.False(result.Matched)
+	expect.Equal("%{POSINT:pid}", result.Failure.Component)
+	expect.Equal(9, result.Failure.Offset)
+
+	result, err = g.Debug("%{HOSTPORT}", "example.com:port")
+	expect.NoError(err)
+	expect.False(result.Matched)
+	expect.Equal("%{POSINT}", result.Failure.Component)
+	expect.Equal([]string{"%{HOSTPORT}"}, result.Failure.Path)
+	expect.Equal(12, result.Failure.Offset)
+}
@@ -0,0 +1,176 @@
+package grok
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+)
+
+// EngineMismatch describes an expression for which two grok instances
+// using different engines return different results.
+type EngineMismatch struct {
+	Expression string
+	Input      string
+	// Engines holds the names of the compared engines
+	Engines [2]string
+	// Err is set if the expression compiles with only one of the engines.
+	// The other fields besides Expression and Engines are empty then.
+	Err error
+	// Matched tells for each engine if the expression matched the input
+	Matched [2]bool
+	// Group is the index of the first capture group with different
+	// values or -1 if only one engine matched.
+	Group int
+	// Field is the name of Group or empty for unnamed groups
+	Field string
+	// Present tells for each engine if Group took part in the match
+	Present [2]bool
+	// Values holds the text captured by Group for each engine
+	Values [2]string
+}
+
+// String formats the mismatch as a single line.
+func (mismatch EngineMismatch) String() string {
+	if mismatch.Err != nil {
+		return fmt.Sprintf("%s: %s", mismatch.Expression, mismatch.Err)
+	}
+
+	describe := func(i int) string {
+		switch {
+		case !mismatch.Matched[i]:
+			return mismatch.Engines[i] + " did not match"
+		case mismatch.Group < 0:
+			return mismatch.Engines[i] + " matched"
+		case !mismatch.Present[i]:
+			return mismatch.Engines[i] + " did not capture"
+		default:
+			return fmt.Sprintf("%s captured %q", mismatch.Engines[i], mismatch.Values[i])
+		}
+	}
+
+	group := ""
+	if mismatch.Group >= 0 {
+		group = fmt.Sprintf(" group %d", mismatch.Group)
+		if len(mismatch.Field) > 0 {
+			group += " (" + mismatch.Field + ")"
+		}
+	}
+	return fmt.Sprintf("%s: %q:%s %s, %s", mismatch.Expression, mismatch.Input, group, describe(0), describe(1))
+}
+
+// CompareEngines compiles each expression with both grok instances and
+// matches it against all inputs. The instances are expected to know the
+// same patterns but use different engines, see Config.Engine.
+// All capture groups, named or not, are compared. For each expression and
+// input at most one mismatch is returned, describing the first group with
+// different results. Expressions that compile with only one of the engines
+// are reported once with Err set, expressions that fail with both are
+// skipped.
+func CompareEngines(first, second *Grok, expressions []string, inputs []string) []EngineMismatch {
+	engines := [2]string{first.engine.Name(), second.engine.Name()}
+	mismatches := []EngineMismatch{}
+
+	for _, expression := range expressions {
+		firstCompiled, firstErr := first.Compile(expression)
+		secondCompiled, secondErr := second.Compile(expression)
+		switch {
+		case firstErr != nil && secondErr != nil:
+			continue
+		case firstErr != nil || secondErr != nil:
+			err := firstErr
+			if err == nil {
+				err = secondErr
+			}
+			mismatches = append(mismatches, EngineMismatch{Expression: expression, Engines: engines, Err: err})
+			continue
+		}
+
+		matchers := [2]Matcher{firstCompiled.regexp.NewMatcher(), secondCompiled.regexp.NewMatcher()}
+		groups := minInt(firstCompiled.regexp.Groups(), secondCompiled.regexp.Groups())
+
+		for _, input := range inputs {
+			mismatch := EngineMismatch{
+				Expression: expression,
+				Input:      input,
+				Engines:    engines,
+				Group:      -1,
+				Matched:    [2]bool{matchers[0].MatchString(input), matchers[1].MatchString(input)},
+			}
+
+			if mismatch.Matched[0] != mismatch.Matched[1] {
+				mismatches = append(mismatches, mismatch)
+				continue
+			}
+			if !mismatch.Matched[0] {
+				continue
+			}
+
+			for group := 0; group <= groups; group++ {
+				present := [2]bool{matchers[0].Present(group), matchers[1].Present(group)}
+				values := [2]string{matchers[0].GroupString(group), matchers[1].GroupString(group)}
+				if present[0] == present[1] && values[0] == values[1] {
+					continue
+				}
+				mismatch.Group = group
+				mismatch.Field = firstCompiled.groupIdToName[group]
+				mismatch.Present = present
+				mismatch.Values = values
+				mismatches = append(mismatches, mismatch)
+				break
+			}
+		}
+	}
+	return mismatches
+}
+
+// differentialAlphabet holds characters that often trigger differences
+// between engines, e.g. whitespace other than space and tab, multibyte
+// characters and characters with a special meaning in log formats.
+const differentialAlphabet = "aZz09 \t\v\f\r\n:./-_[](){}\"'<>,;=@%+*#\\|ä\u00a0\u3000"
+
+// GenerateInputs returns n inputs for CompareEngines. Each input is one of
+// the samples with a few random insertions, deletions, replacements or
+// duplications applied, or a random string if no samples are given.
+// The same seed always returns the same inputs.
+func GenerateInputs(samples []string, n int, seed int64) []string {
+	random := rand.New(rand.NewSource(seed))
+	alphabet := []rune(differentialAlphabet)
+	randomText := func(length int) string {
+		var text strings.Builder
+		for i := 0; i < length; i++ {
+			text.WriteRune(alphabet[random.Intn(len(alphabet))])
+		}
+		return text.String()
+	}
+
+	inputs := make([]string, 0, n)
+	for len(inputs) < n {
+		if len(samples) == 0 {
+			inputs = append(inputs, randomText(random.Intn(32)))
+			continue
+		}
+
+		input := samples[random.Intn(len(samples))]
+		for edits := 1 + random.Intn(3); edits > 0; edits-- {
+			pos := 0
+			if len(input) > 0 {
+				pos = random.Intn(len(input) + 1)
+			}
+			switch random.Intn(4) {
+			case 0: // insert
+				input = input[:pos] + randomText(1+random.Intn(3)) + input[pos:]
+			case 1: // delete
+				end := minInt(len(input), pos+1+random.Intn(4))
+				input = input[:pos] + input[end:]
+			case 2: // replace
+				end := minInt(len(input), pos+1)
+				input = input[:pos] + randomText(1) + input[end:]
+			case 3: // duplicate
+				end := minInt(len(input), pos+1+random.Intn(8))
+				input = input[:end] + input[pos:end] + input[end:]
+			}
+		}
+		inputs = append(inputs, input)
+	}
+	return inputs
+}
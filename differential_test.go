@@ -0,0 +1,55 @@
+package grok
+
+import (
+	"github.com/trivago/tgo/ttesting"
+	"testing"
+)
+
+// caselessEngine behaves like RE2 but matches case insensitive, so it
+// differs from RE2 in a predictable way.
+type caselessEngine struct{}
+
+func (engine caselessEngine) Name() string {
+	return "caseless"
+}
+
+func (engine caselessEngine) Compile(expression string) (Regexp, error) {
+	return RE2.Compile("(?i)" + expression)
+}
+
+func TestCompareEngines(t *testing.T) {
+	expect := ttesting.NewExpect(t)
+
+	re2, err := New(Config{Engine: RE2})
+	expect.NoError(err)
+	caseless, err := New(Config{Engine: caselessEngine{}})
+	expect.NoError(err)
+
+	expressions := []string{`%{NUMBER:n} (?<word>[a-z]+)`, `x(?=y)`}
+	mismatches := CompareEngines(re2, caseless, expressions, []string{"1 abc", "1 ABC", "1 abC", "x"})
+	expect.Equal(2, len(mismatches))
+	if len(mismatches) != 2 {
+		return
+	}
+
+	expect.Equal("1 ABC", mismatches[0].Input)
+	expect.Equal(-1, mismatches[0].Group)
+	expect.Equal([2]bool{false, true}, mismatches[0].Matched)
+	expect.Equal([2]string{"re2", "caseless"}, mismatches[0].Engines)
+
+	expect.Equal("1 abC", mismatches[1].Input)
+	expect.Equal(0, mismatches[1].Group)
+	expect.Equal([2]string{"1 ab", "1 abC"}, mismatches[1].Values)
+	expect.Equal(`%{NUMBER:n} (?<word>[a-z]+): "1 abC": group 0 re2 captured "1 ab", caseless captured "1 abC"`, mismatches[1].String())
+}
+
+func TestGenerateInputs(t *testing.T) {
+	expect := ttesting.NewExpect(t)
+
+	inputs := GenerateInputs([]string{"127.0.0.1 GET /index.html"}, 20, 1)
+	expect.Equal(20, len(inputs))
+	expect.Equal(inputs, GenerateInputs([]string{"127.0.0.1 GET /index.html"}, 20, 1))
+
+	random := GenerateInputs(nil, 5, 1)
+	expect.Equal(5, len(random))
+}
@@ -0,0 +1,233 @@
+package grok
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// discoveryPatterns lists the patterns Discover tries to recognize, ordered
+// from most to least specific. If two patterns match the same text, the one
+// listed first is used.
+var discoveryPatterns = []string{
+	"TIMESTAMP_ISO8601",
+	"HTTPDATE",
+	"DATESTAMP_RFC2822",
+	"DATESTAMP_RFC822",
+	"DATESTAMP_OTHER",
+	"HTTPDERROR_DATE",
+	"SYSLOGTIMESTAMP",
+	"DATESTAMP",
+	"UUID",
+	"MAC",
+	"IP",
+	"EMAILADDRESS",
+	"URI",
+	"LOGLEVEL",
+	"QUOTEDSTRING",
+	"NUMBER",
+}
+
+// discoveryToken is a piece of a sample line, either recognized as a known
+// pattern or a literal.
+type discoveryToken struct {
+	pattern string
+	text    string
+}
+
+// discoveryCandidate is a pattern compiled to match at the start of a text
+type discoveryCandidate struct {
+	name     string
+	compiled *CompiledGrok
+}
+
+// Discover proposes a grok expression matching all given samples.
+// Known patterns like timestamps, IPs or numbers are recognized in the
+// samples and replaced by named references, e.g. %{IP:ip}. Text that differs
+// between samples is generalized to %{WORD}, %{NOTSPACE} or %{DATA}.
+// Only patterns that are known to this grok instance are used.
+// An error is returned if no expression matching all samples could be found.
+func (grok Grok) Discover(samples []string) (string, error) {
+	if len(samples) == 0 {
+		return "", errors.New("no samples given")
+	}
+
+	candidates := make([]discoveryCandidate, 0, len(discoveryPatterns))
+	for _, name := range discoveryPatterns {
+		if _, known := grok.patterns[name]; !known {
+			continue
+		}
+		compiled, err := grok.Compile(fmt.Sprintf("^(?:%%{%s})", name))
+		if err != nil {
+			return "", err
+		}
+		candidates = append(candidates, discoveryCandidate{name, compiled})
+	}
+
+	tokenized := make([][]discoveryToken, len(samples))
+	for i, sample := range samples {
+		tokenized[i] = tokenizeSample(sample, candidates)
+	}
+
+	expression := grok.alignTokens(tokenized, candidates)
+	verify, err := grok.Compile("^(?:" + expression + ")$")
+	if err != nil {
+		return "", err
+	}
+	for _, sample := range samples {
+		if !verify.MatchString(sample) {
+			return "", fmt.Errorf("proposed expression %s does not match %q", expression, sample)
+		}
+	}
+	return expression, nil
+}
+
+// tokenizeSample splits a sample into known patterns and literals. At each
+// position the longest match of all candidates is used, as long as it starts
+// and ends at a word boundary.
+func tokenizeSample(sample string, candidates []discoveryCandidate) []discoveryToken {
+	tokens := []discoveryToken{}
+	for i := 0; i < len(sample); {
+		best := discoveryToken{}
+		if i == 0 || !isWordChar(sample[i-1]) || !isWordChar(sample[i]) {
+			for _, candidate := range candidates {
+				matcher := candidate.compiled.regexp.NewMatcher()
+				if !matcher.MatchString(sample[i:]) {
+					continue
+				}
+				end := i + matcher.GroupIndices(0)[1]
+				if end > i+len(best.text) && (end == len(sample) || !isWordChar(sample[end]) || !isWordChar(sample[end-1])) {
+					best = discoveryToken{candidate.name, sample[i:end]}
+				}
+			}
+		}
+
+		if len(best.text) == 0 {
+			end := i + 1
+			if isWordChar(sample[i]) {
+				for end < len(sample) && isWordChar(sample[end]) {
+					end++
+				}
+			}
+			best = discoveryToken{"", sample[i:end]}
+		}
+
+		tokens = append(tokens, best)
+		i += len(best.text)
+	}
+	return tokens
+}
+
+// alignTokens builds an expression from the tokens of all samples.
+// Positions where the samples differ are generalized. If the samples have a
+// different structure, the first differing position and all following text
+// is matched by %{GREEDYDATA}.
+func (grok Grok) alignTokens(tokenized [][]discoveryToken, candidates []discoveryCandidate) string {
+	var expression strings.Builder
+	aliasCount := make(map[string]int)
+	reference := func(pattern, alias string) {
+		aliasCount[alias]++
+		if aliasCount[alias] > 1 {
+			alias = fmt.Sprintf("%s%d", alias, aliasCount[alias])
+		}
+		fmt.Fprintf(&expression, "%%{%s:%s}", pattern, alias)
+	}
+
+	for pos := 0; pos < len(tokenized[0]); pos++ {
+		column := make([]discoveryToken, 0, len(tokenized))
+		for _, tokens := range tokenized {
+			if pos >= len(tokens) {
+				break
+			}
+			column = append(column, tokens[pos])
+		}
+
+		if len(column) < len(tokenized) {
+			reference("GREEDYDATA", "message")
+			return expression.String()
+		}
+
+		pattern, isLiteral := column[0].pattern, true
+		for _, token := range column {
+			if token.pattern != pattern {
+				pattern = ""
+				isLiteral = false
+				break
+			}
+			if token.text != column[0].text {
+				isLiteral = false
+			}
+		}
+
+		switch {
+		case len(pattern) > 0:
+			reference(pattern, strings.ToLower(pattern))
+
+		case isLiteral:
+			expression.WriteString(escapeLiteral(column[0].text))
+
+		default:
+			reference(grok.generalize(column, candidates), "field")
+		}
+	}
+
+	// Samples with more tokens than the first one get a trailing catch-all
+	for _, tokens := range tokenized[1:] {
+		if len(tokens) > len(tokenized[0]) {
+			expression.WriteString("%{GREEDYDATA:message}")
+			break
+		}
+	}
+	return expression.String()
+}
+
+// generalize returns the most specific pattern matching all texts of a
+// column of tokens.
+func (grok Grok) generalize(column []discoveryToken, candidates []discoveryCandidate) string {
+	for _, candidate := range candidates {
+		matchesAll := true
+		for _, token := range column {
+			matcher := candidate.compiled.regexp.NewMatcher()
+			if !matcher.MatchString(token.text) || matcher.GroupIndices(0)[1] != len(token.text) {
+				matchesAll = false
+				break
+			}
+		}
+		if matchesAll {
+			return candidate.name
+		}
+	}
+
+	isWord, hasSpace := true, false
+	for _, token := range column {
+		for i := 0; i < len(token.text); i++ {
+			isWord = isWord && isWordChar(token.text[i])
+			hasSpace = hasSpace || token.text[i] == ' ' || token.text[i] == '\t'
+		}
+	}
+	switch {
+	case isWord:
+		return "WORD"
+	case !hasSpace:
+		return "NOTSPACE"
+	default:
+		return "DATA"
+	}
+}
+
+// escapeLiteral escapes all characters with a special meaning in grok
+// expressions.
+func escapeLiteral(text string) string {
+	var escaped strings.Builder
+	for i := 0; i < len(text); i++ {
+		if strings.IndexByte(`\.+*?()|[]{}^$#%`, text[i]) >= 0 {
+			escaped.WriteByte('\\')
+		}
+		escaped.WriteByte(text[i])
+	}
+	return escaped.String()
+}
+
+func isWordChar(c byte) bool {
+	return c == '_' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
@@ -0,0 +1,30 @@
+package grok
+
+import (
+	"github.com/trivago/tgo/ttesting"
+	"testing"
+)
+
+func TestDiscover(t *testing.T) {
+	expect := ttesting.NewExpect(t)
+
+	g, err := New(Config{})
+	expect.NoError(err)
+
+	expression, err := g.Discover([]string{
+		`2017-07-10T22:30:23Z INFO 10.0.0.1 GET took 12 ms`,
+		`2017-07-11T08:00:01Z WARN 192.168.1.20 POST took 1.5 ms`,
+	})
+	expect.NoError(err)
+	expect.Equal(`%{TIMESTAMP_ISO8601:timestamp_iso8601} %{LOGLEVEL:loglevel} %{IP:ip} %{WORD:field} took %{NUMBER:number} ms`, expression)
+
+	expression, err = g.Discover([]string{
+		`user=alice id=550e8400-e29b-41d4-a716-446655440000 "hello world"`,
+		`user=bob id=550e8400-e29b-41d4-a716-446655440001 "bye" extra`,
+	})
+	expect.NoError(err)
+	expect.Equal(`user=%{WORD:field} id=%{UUID:uuid} %{QUOTEDSTRING:quotedstring}%{GREEDYDATA:message}`, expression)
+
+	_, err = g.Discover([]string{})
+	expect.NotNil(err)
+}
@@ -0,0 +1,331 @@
+package grok
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Dissector splits lines at the literal delimiters of a dissect expression
+// like "%{ts} %{+ts} %{level} [%{thread}] %{msg}" without using regular
+// expressions. Use Grok.CompileDissect to create a Dissector.
+//
+// Each %{key} captures the text up to the delimiter following it, the last
+// key captures the rest of the line. Keys support these modifiers:
+//
+//	%{+key}       appends the value to key, separated by AppendSeparator
+//	%{+key/2}     appends in the given order instead of the order in the line
+//	%{?key}, %{}  skips the value
+//	%{*key}       uses the value as the name of the field set by %{&key}
+//	%{&key}       sets the field named by %{*key} to the value
+//	%{key->}      skips repeated delimiters following the value
+//	%{key:IP}     requires the value to match a grok pattern and adds its
+//	              named captures
+//	%{key:NUMBER:int}
+//	              also converts the value like %{NUMBER:key:int} does
+type Dissector struct {
+	// AppendSeparator is put between appended values, a space by default
+	AppendSeparator string
+
+	expression string
+	prefix     string
+	fields     []dissectField
+	names      []string
+	// appended holds the names of keys made of more than one value
+	appended map[string]bool
+}
+
+// dissectField is a key of a dissect expression and the delimiter that
+// follows it
+type dissectField struct {
+	name      string
+	modifier  byte
+	order     int
+	padded    bool
+	delimiter string
+	typeName  string
+	pattern   *CompiledGrok
+}
+
+// Modifiers of dissect keys
+const (
+	dissectAppend    = '+'
+	dissectSkip      = '?'
+	dissectReference = '*'
+	dissectValue     = '&'
+)
+
+// CompileDissect compiles a dissect expression, see Dissector. Patterns
+// referenced by keys are compiled with the patterns of this instance and key
+// names are converted by Config.NormalizeName.
+func (grok Grok) CompileDissect(expression string) (*Dissector, error) {
+	dissector := &Dissector{
+		AppendSeparator: " ",
+		expression:      expression,
+		appended:        make(map[string]bool),
+	}
+
+	rest := expression
+	start := strings.Index(rest, "%{")
+	if start < 0 {
+		return nil, errors.New("dissect expression without keys")
+	}
+	dissector.prefix, rest = rest[:start], rest[start:]
+
+	known := make(map[string]bool)
+	for len(rest) > 0 {
+		end := strings.IndexByte(rest, '}')
+		if end < 0 {
+			return nil, fmt.Errorf("unterminated key in dissect expression %s", expression)
+		}
+		field, err := grok.parseDissectKey(rest[2:end])
+		if err != nil {
+			return nil, err
+		}
+		rest = rest[end+1:]
+
+		next := strings.Index(rest, "%{")
+		if next < 0 {
+			next = len(rest)
+		}
+		field.delimiter, rest = rest[:next], rest[next:]
+		if len(field.delimiter) == 0 && len(rest) > 0 {
+			return nil, fmt.Errorf("keys must be separated by a delimiter in dissect expression %s", expression)
+		}
+
+		dissector.fields = append(dissector.fields, field)
+		if field.modifier == dissectSkip || field.modifier == dissectReference || field.modifier == dissectValue {
+			continue
+		}
+		if known[field.name] || field.modifier == dissectAppend {
+			dissector.appended[field.name] = true
+		}
+		if !known[field.name] {
+			known[field.name] = true
+			dissector.names = append(dissector.names, field.name)
+		}
+	}
+	return dissector, nil
+}
+
+// parseDissectKey parses the content of a %{...} key
+func (grok Grok) parseDissectKey(key string) (dissectField, error) {
+	field := dissectField{}
+
+	parts := strings.SplitN(key, ":", 3)
+	key = parts[0]
+	if strings.HasSuffix(key, "->") {
+		field.padded = true
+		key = strings.TrimSuffix(key, "->")
+	}
+
+	if len(key) == 0 {
+		field.modifier = dissectSkip
+	} else if strings.IndexByte("+?*&", key[0]) >= 0 {
+		field.modifier = key[0]
+		key = key[1:]
+	}
+	if field.modifier == dissectAppend {
+		if slash := strings.LastIndexByte(key, '/'); slash >= 0 {
+			order, err := strconv.Atoi(key[slash+1:])
+			if err != nil {
+				return field, fmt.Errorf("invalid append order in %%{%s}", parts[0])
+			}
+			field.order, key = order, key[:slash]
+		}
+	}
+	field.name = key
+	if grok.normalize != nil && len(key) > 0 {
+		field.name = grok.normalize(key)
+	}
+
+	if len(parts) > 1 && len(parts[1]) > 0 {
+		compiled, err := grok.Compile("^(?:%{" + parts[1] + "})$")
+		if err != nil {
+			return field, err
+		}
+		field.pattern = compiled
+	}
+	if len(parts) > 2 {
+		if _, err := castValue("0", parts[2]); err != nil {
+			return field, fmt.Errorf("unknown type %s in %%{%s}", parts[2], strings.Join(parts, ":"))
+		}
+		field.typeName = parts[2]
+	}
+	return field, nil
+}
+
+// dissect splits the text into the values of each field. False is returned
+// if a delimiter is missing.
+func (dissector *Dissector) dissect(text string) ([]string, bool) {
+	if !strings.HasPrefix(text, dissector.prefix) {
+		return nil, false
+	}
+	position := len(dissector.prefix)
+
+	values := make([]string, len(dissector.fields))
+	for i, field := range dissector.fields {
+		if len(field.delimiter) == 0 {
+			values[i] = text[position:]
+			position = len(text)
+			continue
+		}
+
+		end := strings.Index(text[position:], field.delimiter)
+		if end < 0 {
+			return nil, false
+		}
+		values[i] = text[position : position+end]
+		position += end + len(field.delimiter)
+		if field.padded {
+			for strings.HasPrefix(text[position:], field.delimiter) {
+				position += len(field.delimiter)
+			}
+		}
+	}
+	return values, true
+}
+
+// Match returns true if the given data contains all delimiters of the
+// expression and all values match their patterns.
+func (dissector *Dissector) Match(data []byte) bool {
+	matched, _ := dissector.MatchAgainst(string(data))
+	return matched
+}
+
+// MatchString returns true if the given text contains all delimiters of the
+// expression and all values match their patterns.
+func (dissector *Dissector) MatchString(text string) bool {
+	matched, _ := dissector.MatchAgainst(text)
+	return matched
+}
+
+// MatchAgainst works like CompiledGrok.MatchAgainst. Fields of keys with a
+// pattern hold the captures of the pattern, too.
+func (dissector *Dissector) MatchAgainst(text string) (bool, map[string]string) {
+	values := make(map[string]string)
+	dissected, matched := dissector.dissect(text)
+	if !matched {
+		return false, values
+	}
+
+	type appended struct {
+		order int
+		value string
+	}
+	var appends map[string][]appended
+	var references map[string]string
+
+	for i, field := range dissector.fields {
+		value := dissected[i]
+		if field.pattern != nil {
+			matched, captures := field.pattern.MatchAgainst(value)
+			if !matched {
+				return false, map[string]string{}
+			}
+			for name, capture := range captures {
+				if len(name) > 0 {
+					values[name] = capture
+				}
+			}
+		}
+
+		switch field.modifier {
+		case dissectSkip:
+		case dissectReference:
+			if references == nil {
+				references = make(map[string]string)
+			}
+			references[field.name] = value
+		case dissectValue:
+			// Stored once the name is known
+		default:
+			if !dissector.appended[field.name] {
+				values[field.name] = value
+				break
+			}
+			// %{key} starts the value that %{+key} appends to
+			if appends == nil {
+				appends = make(map[string][]appended)
+			}
+			appends[field.name] = append(appends[field.name], appended{field.order, value})
+		}
+	}
+
+	for name, parts := range appends {
+		sort.SliceStable(parts, func(i, j int) bool {
+			return parts[i].order < parts[j].order
+		})
+		joined := make([]string, len(parts))
+		for i, part := range parts {
+			joined[i] = part.value
+		}
+		values[name] = strings.Join(joined, dissector.AppendSeparator)
+	}
+
+	for i, field := range dissector.fields {
+		if field.modifier == dissectValue {
+			if name, known := references[field.name]; known {
+				values[name] = dissected[i]
+			}
+		}
+	}
+	return true, values
+}
+
+// MatchAgainstTyped works like CompiledGrok.MatchAgainstTyped, converting
+// the values of keys like %{bytes:NUMBER:int} and the typed captures of
+// their patterns.
+func (dissector *Dissector) MatchAgainstTyped(text string) (bool, map[string]interface{}, error) {
+	matched, values := dissector.MatchAgainst(text)
+	typed := make(map[string]interface{}, len(values))
+	if !matched {
+		return false, typed, nil
+	}
+
+	for name, value := range values {
+		typed[name] = value
+	}
+	for _, field := range dissector.fields {
+		if field.pattern != nil {
+			captured := make(map[string]string)
+			for _, name := range field.pattern.FieldNames() {
+				if value, found := values[name]; found {
+					captured[name] = value
+				}
+			}
+			converted, err := field.pattern.ConvertTypes(captured)
+			if err != nil {
+				return false, typed, err
+			}
+			for name, value := range converted {
+				typed[name] = value
+			}
+		}
+
+		value, found := values[field.name]
+		if len(field.typeName) == 0 || !found || field.modifier == dissectSkip {
+			continue
+		}
+		converted, err := castValue(value, field.typeName)
+		if err != nil {
+			return false, typed, err
+		}
+		typed[field.name] = converted
+	}
+	return true, typed, nil
+}
+
+// FieldNames returns the names of the keys of the expression in the order
+// they appear. Fields named by %{*key} and the captures of patterns are not
+// included.
+func (dissector *Dissector) FieldNames() []string {
+	return append([]string(nil), dissector.names...)
+}
+
+// String returns the dissect expression this object was compiled from.
+func (dissector *Dissector) String() string {
+	return dissector.expression
+}
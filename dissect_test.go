@@ -0,0 +1,133 @@
+package grok
+
+import (
+	"github.com/trivago/tgo/ttesting"
+	"testing"
+)
+
+func TestDissect(t *testing.T) {
+	expect := ttesting.NewExpect(t)
+	g, _ := New(Config{NamedCapturesOnly: true})
+
+	dissector, err := g.CompileDissect("%{ts} %{+ts} %{level} [%{thread}] %{msg}")
+	expect.NoError(err)
+	expect.Equal([]string{"ts", "level", "thread", "msg"}, dissector.FieldNames())
+
+	matched, values := dissector.MatchAgainst("2021-03-04 10:22:01 INFO [main] server started on :8080")
+	expect.True(matched)
+	expect.Equal(map[string]string{
+		"ts":     "2021-03-04 10:22:01",
+		"level":  "INFO",
+		"thread": "main",
+		"msg":    "server started on :8080",
+	}, values)
+
+	matched, values = dissector.MatchAgainst("2021-03-04 10:22:01 INFO main")
+	expect.False(matched)
+	expect.Equal(0, len(values))
+	expect.False(dissector.MatchString("no delimiters"))
+	expect.True(dissector.Match([]byte("a b c [d] e")))
+}
+
+func TestDissectModifiers(t *testing.T) {
+	expect := ttesting.NewExpect(t)
+	g, _ := New(Config{})
+
+	dissector, err := g.CompileDissect("<%{?pri}>%{+name/2} %{+name/1}|%{*key}=%{&key}|%{} %{level->} %{msg}")
+	expect.NoError(err)
+	dissector.AppendSeparator = ","
+	expect.Equal([]string{"name", "level", "msg"}, dissector.FieldNames())
+
+	matched, values := dissector.MatchAgainst("<13>john doe|role=admin|x WARN   disk almost full")
+	expect.True(matched)
+	expect.Equal(map[string]string{
+		"name":  "doe,john",
+		"role":  "admin",
+		"level": "WARN",
+		"msg":   "disk almost full",
+	}, values)
+
+	expect.False(dissector.MatchString("13>john doe|role=admin|x WARN disk"))
+
+	for _, invalid := range []string{"no keys", "%{a}%{b}", "%{a", "%{+a/x} b", "%{a:NOTAPATTERN}", "%{a::bool}"} {
+		_, err = g.CompileDissect(invalid)
+		expect.NotNil(err)
+	}
+}
+
+func TestDissectPatterns(t *testing.T) {
+	expect := ttesting.NewExpect(t)
+	g, _ := New(Config{NamedCapturesOnly: true})
+
+	dissector, err := g.CompileDissect("%{client:IP} %{request:URIPATHPARAM} %{status::int} %{bytes:NUMBER:float} %{rest}")
+	expect.NoError(err)
+	expect.Equal("%{client:IP} %{request:URIPATHPARAM} %{status::int} %{bytes:NUMBER:float} %{rest}", dissector.String())
+
+	matched, values := dissector.MatchAgainst("10.0.0.1 /index.php?id=1 404 207 extra fields")
+	expect.True(matched)
+	expect.Equal("10.0.0.1", values["client"])
+	expect.Equal("/index.php?id=1", values["request"])
+	expect.Equal("404", values["status"])
+	expect.Equal("extra fields", values["rest"])
+
+	matched, typed, err := dissector.MatchAgainstTyped("10.0.0.1 /index.php?id=1 404 207 extra fields")
+	expect.True(matched)
+	expect.NoError(err)
+	expect.Equal(404, typed["status"])
+	expect.Equal(207.0, typed["bytes"])
+
+	// Values not matching their pattern fail the whole line
+	expect.False(dissector.MatchString("localhost /index.php 404 207 -"))
+
+	matched, _, err = dissector.MatchAgainstTyped("10.0.0.1 / x 207 -")
+	expect.True(matched == false && err != nil)
+}
+
+func TestDissectNestedCaptures(t *testing.T) {
+	expect := ttesting.NewExpect(t)
+	g, _ := New(Config{
+		NamedCapturesOnly: true,
+		Patterns:          map[string]string{"ORIGIN": "%{IP:ip}:%{POSINT:port:int}"},
+	})
+
+	// The captures of the pattern are added next to the key
+	dissector, err := g.CompileDissect("%{ts} %{origin:ORIGIN} %{msg}")
+	expect.NoError(err)
+
+	matched, typed, err := dissector.MatchAgainstTyped("12:00 10.1.2.3:443 handshake done")
+	expect.True(matched)
+	expect.NoError(err)
+	expect.Equal(map[string]interface{}{
+		"ts":     "12:00",
+		"origin": "10.1.2.3:443",
+		"ip":     "10.1.2.3",
+		"port":   443,
+		"msg":    "handshake done",
+	}, typed)
+}
+
+const dissectLine = `127.0.0.1 - - [23/Apr/2014:22:58:32 +0200] "GET /index.php HTTP/1.1" 404 207`
+
+var resultDissect map[string]string
+
+func BenchmarkDissect(b *testing.B) {
+	g, _ := New(Config{NamedCapturesOnly: true})
+	dissector, _ := g.CompileDissect(`%{clientip} %{ident} %{auth} [%{timestamp}] "%{verb} %{request} HTTP/%{httpversion}" %{response} %{bytes}`)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		_, resultDissect = dissector.MatchAgainst(dissectLine)
+	}
+}
+
+func BenchmarkDissectGrok(b *testing.B) {
+	g, _ := New(Config{NamedCapturesOnly: true})
+	compiled, _ := g.Compile("%{COMMONAPACHELOG}")
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		_, resultDissect = compiled.MatchAgainst(dissectLine)
+	}
+}
@@ -0,0 +1,69 @@
+package grok
+
+import (
+	"strings"
+)
+
+// ECSDefaultFields maps the capture names of DefaultPatterns to fields of the
+// Elastic Common Schema (ECS). Names without an ECS equivalent are moved
+// below a vendor prefix, e.g. "apache.".
+var ECSDefaultFields = map[string]string{
+	"agent":              "user_agent.original",
+	"auth":               "user.name",
+	"bytes":              "http.response.body.bytes",
+	"client":             "source.address",
+	"clientip":           "source.address",
+	"clientport":         "source.port",
+	"errorcode":          "error.code",
+	"errormsg":           "message",
+	"facility":           "log.syslog.facility.code",
+	"httpversion":        "http.version",
+	"ident":              "apache.access.user.identity",
+	"loglevel":           "log.level",
+	"logsource":          "host.hostname",
+	"module":             "apache.error.module",
+	"pid":                "process.pid",
+	"port":               "url.port",
+	"priority":           "log.syslog.priority",
+	"program":            "process.name",
+	"proxy_errorcode":    "apache.error.proxy.error.code",
+	"proxy_errormessage": "apache.error.proxy.error.message",
+	"rawrequest":         "apache.access.request_line",
+	"referrer":           "http.request.referrer",
+	"request":            "url.original",
+	"response":           "http.response.status_code",
+	"tid":                "process.thread.id",
+	"verb":               "http.request.method",
+}
+
+// ECSDefaultPatterns are the DefaultPatterns with the capture names mapped by
+// ECSDefaultFields. New uses them instead of DefaultPatterns if
+// Config.ECSCompatibility is set.
+var ECSDefaultPatterns = RenameFields(DefaultPatterns, ECSDefaultFields)
+
+// RenameFields returns a copy of patterns with the named captures renamed as
+// listed in fields, e.g. "clientip" to "source.address". Type hints are kept
+// and captures not listed in fields keep their name.
+func RenameFields(patterns map[string]string, fields map[string]string) map[string]string {
+	renamed := make(map[string]string, len(patterns))
+	for name, definition := range patterns {
+		definition = namedReference.ReplaceAllStringFunc(definition, func(reference string) string {
+			parts := strings.SplitN(reference[2:len(reference)-1], ":", 3)
+			if len(parts) < 2 {
+				return reference
+			}
+			if field, mapped := fields[parts[1]]; mapped {
+				parts[1] = field
+			}
+			return "%{" + strings.Join(parts, ":") + "}"
+		})
+		definition = replacementReference.ReplaceAllStringFunc(definition, func(reference string) string {
+			if field, mapped := fields[reference[3:len(reference)-1]]; mapped {
+				return "(?<" + field + ">"
+			}
+			return reference
+		})
+		renamed[name] = definition
+	}
+	return renamed
+}
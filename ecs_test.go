@@ -0,0 +1,92 @@
+package grok
+
+import (
+	"testing"
+
+	"github.com/rtkjweeks/grok-go-pcre/patterns"
+	"github.com/trivago/tgo/ttesting"
+)
+
+func TestRenameFields(t *testing.T) {
+	expect := ttesting.NewExpect(t)
+
+	renamed := RenameFields(map[string]string{
+		"ACCESS": `%{IP:clientip} %{NUMBER:bytes:int} %{WORD:verb} (?<code>\d+)`,
+		"PLAIN":  `%{IP}`,
+	}, map[string]string{
+		"clientip": "source.ip",
+		"bytes":    "http.response.body.bytes",
+		"code":     "http.response.status_code",
+	})
+
+	expect.Equal(`%{IP:source.ip} %{NUMBER:http.response.body.bytes:int} %{WORD:verb} (?<http.response.status_code>\d+)`, renamed["ACCESS"])
+	expect.Equal(`%{IP}`, renamed["PLAIN"])
+}
+
+func TestECSCompatibility(t *testing.T) {
+	expect := ttesting.NewExpect(t)
+
+	g, err := New(Config{NamedCapturesOnly: true, ECSCompatibility: true})
+	expect.NoError(err)
+
+	compiled, err := g.Compile("%{COMBINEDAPACHELOG}")
+	expect.NoError(err)
+	matched, values := compiled.MatchAgainst(`127.0.0.1 - frank [10/Oct/2000:13:55:36 -0700] "GET /apache_pb.gif HTTP/1.0" 200 2326 "http://www.example.com/start.html" "Mozilla/4.08"`)
+	expect.True(matched)
+	delete(values, "") // unnamed groups
+	expect.Equal(map[string]string{
+		"source.address":              "127.0.0.1",
+		"apache.access.user.identity": "-",
+		"user.name":                   "frank",
+		"timestamp":                   "10/Oct/2000:13:55:36 -0700",
+		"http.request.method":         "GET",
+		"url.original":                "/apache_pb.gif",
+		"http.version":                "1.0",
+		"http.response.status_code":   "200",
+		"http.response.body.bytes":    "2326",
+		"http.request.referrer":       `"http://www.example.com/start.html"`,
+		"user_agent.original":         `"Mozilla/4.08"`,
+	}, values)
+
+	compiled, err = g.Compile("%{HTTPD24_ERRORLOG}")
+	expect.NoError(err)
+	matched, values = compiled.MatchAgainst(`[Mon Aug 31 16:30:47.583782 2015] [proxy_fcgi:error] [pid 28787:tid 140169587934976] (70008)Partial results are valid but processing is incomplete: [client 58.13.45.166:59307] AH01075: Error dispatching request to : (reading input brigade), referer: http://example.com/index.php?id_product=11&controller=product`)
+	expect.True(matched)
+	expect.Equal("proxy_fcgi", values["apache.error.module"])
+	expect.Equal("error", values["log.level"])
+	expect.Equal("28787", values["process.pid"])
+	expect.Equal("140169587934976", values["process.thread.id"])
+	expect.Equal("58.13.45.166", values["source.address"])
+	expect.Equal("59307", values["source.port"])
+	expect.Equal("AH01075", values["error.code"])
+
+	compiled, err = g.Compile("%{SYSLOGBASE}")
+	expect.NoError(err)
+	matched, values = compiled.MatchAgainst("Jun 18 13:02:41 <3.6> web01 sshd[1234]:")
+	expect.True(matched)
+	delete(values, "")
+	expect.Equal(map[string]string{
+		"timestamp":                "Jun 18 13:02:41",
+		"log.syslog.facility.code": "3",
+		"log.syslog.priority":      "6",
+		"host.hostname":            "web01",
+		"process.name":             "sshd",
+		"process.pid":              "1234",
+	}, values)
+}
+
+func TestECSDefaultFields(t *testing.T) {
+	expect := ttesting.NewExpect(t)
+
+	// The grok pack duplicates some default patterns and needs to map their
+	// fields the same way
+	for legacy, field := range patterns.ECSFields["grok"] {
+		expect.Equal(ECSDefaultFields[legacy], field)
+	}
+
+	// Bundles built with legacy field names cannot be used for ECS
+	g, err := New(Config{})
+	expect.NoError(err)
+	_, err = NewFromBundle(g.Bundle(), Config{ECSCompatibility: true})
+	expect.Equal(ErrStaleBundle, err)
+}
@@ -0,0 +1,136 @@
+package grok
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Engine compiles the regular expressions grok expressions are expanded to.
+// Two engines are available: PCRE, which requires cgo and libpcre, and RE2,
+// which is based on the regexp package of the standard library.
+// DefaultEngine is used if Config.Engine is not set.
+type Engine interface {
+	// Name returns a short, lowercase name of the engine, e.g. "pcre".
+	Name() string
+
+	// Compile compiles an expanded grok expression. Named groups are
+	// written as (?<name>...).
+	Compile(expression string) (Regexp, error)
+}
+
+// Regexp is a regular expression compiled by an Engine.
+// A Regexp is safe for concurrent use.
+type Regexp interface {
+	// Groups returns the number of capture groups.
+	Groups() int
+
+	// GroupIndex returns the number of the group with the given name or -1
+	// if there is no such group.
+	GroupIndex(name string) int
+
+	// NewMatcher returns a matcher for this expression.
+	NewMatcher() Matcher
+}
+
+// Matcher matches a Regexp and holds the result of the last match.
+// A Matcher is not safe for concurrent use, but it can be reused to avoid
+// allocations when matching many subjects.
+type Matcher interface {
+	// Match returns true if the expression matches the subject.
+	Match(subject []byte) bool
+
+	// MatchString returns true if the expression matches the subject.
+	MatchString(subject string) bool
+
+	// Present returns true if the given group took part in the last match.
+	Present(group int) bool
+
+	// GroupString returns the text captured by the given group in the last
+	// match or an empty string if the group is not present.
+	GroupString(group int) string
+
+	// GroupIndices returns the start and end offset of the given group in
+	// the last match or nil if the group is not present. Group 0 is the
+	// whole match.
+	GroupIndices(group int) []int
+}
+
+// LimitReporter is implemented by matchers of engines that abort matches
+// exceeding a backtracking limit, like PCRE. Aborted matches are reported as
+// not matching.
+type LimitReporter interface {
+	// LimitExceeded returns true if the last match was aborted because it
+	// exceeded a limit of the engine.
+	LimitExceeded() bool
+}
+
+// StepEngine is implemented by backtracking engines that can abort a match
+// after a given number of steps, like PCRE. Grok.Profile uses it to count
+// the steps of a match by probing with increasing limits.
+type StepEngine interface {
+	Engine
+
+	// CompileLimited compiles an expression like Compile, but matches taking
+	// more than limit steps are aborted and reported by LimitReporter.
+	CompileLimited(expression string, limit int) (Regexp, error)
+}
+
+// DefaultEngine is used by New if Config.Engine is not set. It is PCRE
+// unless the package is built without cgo or with the "nopcre" build tag, in
+// which case it is RE2.
+var DefaultEngine = newDefaultEngine()
+
+// engines holds all engines available in this build by name
+var engines = map[string]Engine{}
+
+func registerEngine(engine Engine) {
+	engines[engine.Name()] = engine
+}
+
+// LookupEngine returns the engine of the given name, e.g. "pcre" or "re2".
+// An error is returned if the engine is not available in this build.
+func LookupEngine(name string) (Engine, error) {
+	engine, known := engines[name]
+	if !known {
+		return nil, fmt.Errorf("regexp engine %s is not available, use one of %v", name, EngineNames())
+	}
+	return engine, nil
+}
+
+// EngineNames returns the sorted names of all engines available in this
+// build.
+func EngineNames() []string {
+	names := make([]string, 0, len(engines))
+	for name := range engines {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// UnsupportedSyntaxError is returned when an expression uses a construct
+// the engine cannot compile, e.g. a lookahead with RE2.
+type UnsupportedSyntaxError struct {
+	// Engine is the name of the engine
+	Engine string
+	// Construct describes the unsupported construct, e.g. "lookahead"
+	Construct string
+	// Text is the part of the expression the construct starts with
+	Text string
+	// Pattern is the name of the pattern using the construct. It is empty
+	// if the construct is used by the compiled expression itself.
+	Pattern string
+	// Offset is the position of the construct in Expression
+	Offset int
+	// Expression is the expression that failed to compile
+	Expression string
+}
+
+// Error returns a description of the construct and where it was found.
+func (err *UnsupportedSyntaxError) Error() string {
+	where := fmt.Sprintf("offset %d of the expanded expression", err.Offset)
+	if len(err.Pattern) > 0 {
+		where = "pattern " + err.Pattern
+	}
+	return fmt.Sprintf("%s engine does not support %s %q used in %s", err.Engine, err.Construct, err.Text, where)
+}
@@ -0,0 +1,8 @@
+//go:build !cgo || nopcre
+// +build !cgo nopcre
+
+package grok
+
+func newDefaultEngine() Engine {
+	return RE2
+}
@@ -0,0 +1,102 @@
+//go:build cgo && !nopcre
+// +build cgo,!nopcre
+
+package grok
+
+import (
+	"strconv"
+
+	"github.com/rtkjweeks/go-pcre"
+)
+
+// PCRE compiles expressions with libpcre. It supports the full PCRE syntax
+// including lookarounds and atomic groups. PCRE requires cgo and is not
+// available when building with the "nopcre" tag.
+var PCRE Engine = pcreEngine{}
+
+type pcreEngine struct{}
+
+type pcreRegexp struct {
+	regexp pcre.Regexp
+}
+
+type pcreMatcher struct {
+	matcher *pcre.Matcher
+	// limited is set if the last match exceeded the match or recursion
+	// limit of libpcre
+	limited bool
+}
+
+func init() {
+	registerEngine(PCRE)
+}
+
+func newDefaultEngine() Engine {
+	return PCRE
+}
+
+func (engine pcreEngine) Name() string {
+	return "pcre"
+}
+
+func (engine pcreEngine) Compile(expression string) (Regexp, error) {
+	compiled, err := pcre.Compile(expression, 0)
+	if err != nil {
+		return nil, err
+	}
+	return pcreRegexp{compiled}, nil
+}
+
+// CompileLimited sets the match limit with a leading (*LIMIT_MATCH=n),
+// which requires libpcre 8.33 or later. The limit counts the calls of the
+// internal match function, i.e. the steps of the backtracking matcher.
+func (engine pcreEngine) CompileLimited(expression string, limit int) (Regexp, error) {
+	return engine.Compile("(*LIMIT_MATCH=" + strconv.Itoa(limit) + ")" + expression)
+}
+
+func (re pcreRegexp) Groups() int {
+	return re.regexp.Groups()
+}
+
+func (re pcreRegexp) GroupIndex(name string) int {
+	index, err := re.regexp.GroupNameToIndex(name)
+	if err != nil {
+		return -1
+	}
+	return index
+}
+
+func (re pcreRegexp) NewMatcher() Matcher {
+	return &pcreMatcher{matcher: re.regexp.NewMatcher()}
+}
+
+func (m *pcreMatcher) Match(subject []byte) bool {
+	return m.result(m.matcher.Exec(subject, 0))
+}
+
+func (m *pcreMatcher) MatchString(subject string) bool {
+	return m.result(m.matcher.ExecString(subject, 0))
+}
+
+// result evaluates the return code of pcre_exec. Exceeded limits are
+// reported by LimitExceeded instead of panicking like pcre.Matcher.Match.
+func (m *pcreMatcher) result(rc int) bool {
+	m.limited = rc == pcre.ERROR_MATCHLIMIT || rc == pcre.ERROR_RECURSIONLIMIT
+	return rc >= 0
+}
+
+func (m *pcreMatcher) LimitExceeded() bool {
+	return m.limited
+}
+
+func (m *pcreMatcher) Present(group int) bool {
+	return m.matcher.Present(group)
+}
+
+func (m *pcreMatcher) GroupString(group int) string {
+	return m.matcher.GroupString(group)
+}
+
+func (m *pcreMatcher) GroupIndices(group int) []int {
+	return m.matcher.GroupIndices(group)
+}
@@ -0,0 +1,266 @@
+package grok
+
+import (
+	"regexp"
+	"strings"
+)
+
+// RE2 compiles expressions with the regexp package of the standard library.
+// It does not need cgo and guarantees matching in linear time, but it does
+// not support PCRE constructs like lookarounds, atomic groups, possessive
+// quantifiers or backreferences. Expressions using them fail to compile
+// with an UnsupportedSyntaxError.
+// PCRE escape sequences without RE2 counterpart, like \h or \e, are
+// translated.
+var RE2 Engine = re2Engine{}
+
+type re2Engine struct{}
+
+type re2Regexp struct {
+	regexp *regexp.Regexp
+}
+
+type re2Matcher struct {
+	regexp  *regexp.Regexp
+	subject string
+	loc     []int
+}
+
+// horizontalSpace is the content of a character class matching the same
+// characters as \h in PCRE
+const horizontalSpace = `\t \x{A0}\x{1680}\x{180E}\x{2000}-\x{200A}\x{202F}\x{205F}\x{3000}`
+
+// verticalSpace is the content of a character class matching the same
+// characters as \v in PCRE
+const verticalSpace = `\n\x0B\f\r\x{85}\x{2028}\x{2029}`
+
+func init() {
+	registerEngine(RE2)
+}
+
+func (engine re2Engine) Name() string {
+	return "re2"
+}
+
+func (engine re2Engine) Compile(expression string) (Regexp, error) {
+	translated, err := translateRE2(expression)
+	if err != nil {
+		return nil, err
+	}
+	compiled, err := regexp.Compile(translated)
+	if err != nil {
+		return nil, err
+	}
+	return re2Regexp{compiled}, nil
+}
+
+func (re re2Regexp) Groups() int {
+	return re.regexp.NumSubexp()
+}
+
+func (re re2Regexp) GroupIndex(name string) int {
+	for index, groupName := range re.regexp.SubexpNames() {
+		if groupName == name && len(name) > 0 {
+			return index
+		}
+	}
+	return -1
+}
+
+func (re re2Regexp) NewMatcher() Matcher {
+	return &re2Matcher{regexp: re.regexp}
+}
+
+func (m *re2Matcher) Match(subject []byte) bool {
+	m.subject = string(subject)
+	m.loc = m.regexp.FindSubmatchIndex(subject)
+	return m.loc != nil
+}
+
+func (m *re2Matcher) MatchString(subject string) bool {
+	m.subject = subject
+	m.loc = m.regexp.FindStringSubmatchIndex(subject)
+	return m.loc != nil
+}
+
+func (m *re2Matcher) Present(group int) bool {
+	return 2*group+1 < len(m.loc) && m.loc[2*group] >= 0
+}
+
+func (m *re2Matcher) GroupString(group int) string {
+	if !m.Present(group) {
+		return ""
+	}
+	return m.subject[m.loc[2*group]:m.loc[2*group+1]]
+}
+
+func (m *re2Matcher) GroupIndices(group int) []int {
+	if !m.Present(group) {
+		return nil
+	}
+	return []int{m.loc[2*group], m.loc[2*group+1]}
+}
+
+// translateRE2 rewrites a PCRE expression into the RE2 syntax. Named groups
+// are converted to (?P<name>...) and escape sequences only known to PCRE are
+// replaced. Constructs that cannot be expressed in RE2 are reported as an
+// UnsupportedSyntaxError.
+func translateRE2(expression string) (string, error) {
+	var out strings.Builder
+	unsupported := func(offset int, construct, text string) error {
+		return &UnsupportedSyntaxError{
+			Engine:     "re2",
+			Construct:  construct,
+			Text:       text,
+			Offset:     offset,
+			Expression: expression,
+		}
+	}
+
+	inClass := false
+	for i := 0; i < len(expression); i++ {
+		c := expression[i]
+		switch {
+		case c == '\\' && i+1 < len(expression):
+			escape := expression[i+1]
+			i++
+			switch escape {
+			case 'h', 'v':
+				chars := horizontalSpace
+				if escape == 'v' {
+					chars = verticalSpace
+				}
+				if inClass {
+					out.WriteString(chars)
+				} else {
+					out.WriteString("[" + chars + "]")
+				}
+			case 'H', 'V':
+				if inClass {
+					return "", unsupported(i-1, "negated escape in a character class", expression[i-1:i+1])
+				}
+				chars := horizontalSpace
+				if escape == 'V' {
+					chars = verticalSpace
+				}
+				out.WriteString("[^" + chars + "]")
+			case 'e':
+				out.WriteString(`\x1B`)
+			case '1', '2', '3', '4', '5', '6', '7', '8', '9', 'g', 'k':
+				return "", unsupported(i-1, "backreference", expression[i-1:i+1])
+			case 'K':
+				return "", unsupported(i-1, "match reset", `\K`)
+			case 'G':
+				return "", unsupported(i-1, "match start anchor", `\G`)
+			case 'Z':
+				return "", unsupported(i-1, "end of subject anchor before newline", `\Z`)
+			case 'R', 'X':
+				return "", unsupported(i-1, "escape sequence", expression[i-1:i+1])
+			default:
+				out.WriteByte('\\')
+				out.WriteByte(escape)
+			}
+
+		case inClass:
+			if c == '[' && strings.HasPrefix(expression[i:], "[:") {
+				end := strings.Index(expression[i:], ":]")
+				if end > 0 {
+					out.WriteString(expression[i : i+end+2])
+					i += end + 1
+					continue
+				}
+			}
+			if c == ']' {
+				inClass = false
+			}
+			out.WriteByte(c)
+
+		case c == '[':
+			inClass = true
+			out.WriteByte(c)
+			// A ']' directly after '[' or '[^' is a literal
+			if strings.HasPrefix(expression[i+1:], "^") {
+				out.WriteByte('^')
+				i++
+			}
+			if strings.HasPrefix(expression[i+1:], "]") {
+				out.WriteByte(']')
+				i++
+			}
+
+		case c == '(' && strings.HasPrefix(expression[i:], "(?"):
+			group := expression[i:]
+			switch {
+			case strings.HasPrefix(group, "(?="), strings.HasPrefix(group, "(?!"):
+				return "", unsupported(i, "lookahead", group[:3])
+			case strings.HasPrefix(group, "(?<="), strings.HasPrefix(group, "(?<!"):
+				return "", unsupported(i, "lookbehind", group[:4])
+			case strings.HasPrefix(group, "(?>"):
+				return "", unsupported(i, "atomic group", group[:3])
+			case strings.HasPrefix(group, "(?|"):
+				return "", unsupported(i, "branch reset group", group[:3])
+			case strings.HasPrefix(group, "(?("):
+				return "", unsupported(i, "conditional group", group[:3])
+			case strings.HasPrefix(group, "(?P="), strings.HasPrefix(group, "(?P>"), strings.HasPrefix(group, "(?&"),
+				strings.HasPrefix(group, "(?R"), len(group) > 2 && (group[2] >= '0' && group[2] <= '9' || group[2] == '+' || group[2] == '-' && len(group) > 3 && group[3] >= '0' && group[3] <= '9'):
+				return "", unsupported(i, "recursion or backreference", group[:3])
+			case strings.HasPrefix(group, "(?#"):
+				end := strings.IndexByte(group, ')')
+				if end < 0 {
+					end = len(group) - 1
+				}
+				i += end
+			case strings.HasPrefix(group, "(?<"), strings.HasPrefix(group, "(?'"):
+				closing := byte('>')
+				if group[2] == '\'' {
+					closing = '\''
+				}
+				end := strings.IndexByte(group[3:], closing)
+				if end < 0 {
+					out.WriteString(group[:3])
+					i += 2
+					continue
+				}
+				out.WriteString("(?P<" + group[3:3+end] + ">")
+				i += 3 + end
+			default:
+				out.WriteString("(?")
+				i++
+			}
+
+		case c == '*' || c == '+' || c == '?' || c == '}':
+			out.WriteByte(c)
+			if isQuantifierEnd(expression, i) && i+1 < len(expression) && expression[i+1] == '+' {
+				return "", unsupported(i, "possessive quantifier", expression[i:i+2])
+			}
+
+		default:
+			out.WriteByte(c)
+		}
+	}
+	return out.String(), nil
+}
+
+// isQuantifierEnd returns true if the character at pos ends a quantifier,
+// i.e. it is not the '?' of a lazy quantifier and '}' closes a counted
+// repetition like {1,3}.
+func isQuantifierEnd(expression string, pos int) bool {
+	switch expression[pos] {
+	case '?':
+		// a?+ is possessive, a+? and a*? are lazy, (? is a group
+		if pos == 0 {
+			return false
+		}
+		previous := expression[pos-1]
+		return previous != '(' && previous != '*' && previous != '+' && previous != '?' && previous != '}' ||
+			pos > 1 && expression[pos-2] == '\\'
+	case '}':
+		start := strings.LastIndexByte(expression[:pos], '{')
+		if start < 0 {
+			return false
+		}
+		counts := expression[start+1 : pos]
+		return len(counts) > 0 && strings.Trim(counts, "0123456789,") == "" && counts[0] != ','
+	}
+	return true
+}
@@ -0,0 +1,73 @@
+package grok
+
+import (
+	"github.com/trivago/tgo/ttesting"
+	"testing"
+)
+
+func TestRE2Translate(t *testing.T) {
+	expect := ttesting.NewExpect(t)
+
+	translated, err := translateRE2(`(?<host>\w+)\h+[\h,](?:a+?|b*)\(?x\)?[]?]{2}`)
+	expect.NoError(err)
+	expect.Equal(`(?P<host>\w+)[`+horizontalSpace+`]+[`+horizontalSpace+`,](?:a+?|b*)\(?x\)?[]?]{2}`, translated)
+
+	unsupported := map[string]string{
+		`a(?=b)`:     "lookahead",
+		`a(?!b)`:     "lookahead",
+		`(?<=a)b`:    "lookbehind",
+		`(?<!a)b`:    "lookbehind",
+		`(?>a+)b`:    "atomic group",
+		`a++b`:       "possessive quantifier",
+		`a?+b`:       "possessive quantifier",
+		`a{1,3}+b`:   "possessive quantifier",
+		`(a)\1`:      "backreference",
+		`(?<x>a)\kx`: "backreference",
+		`a\Z`:        "end of subject anchor before newline",
+	}
+	for expression, construct := range unsupported {
+		_, err := translateRE2(expression)
+		syntaxErr, isUnsupported := err.(*UnsupportedSyntaxError)
+		expect.True(isUnsupported)
+		if isUnsupported {
+			expect.Equal(construct, syntaxErr.Construct)
+		}
+	}
+}
+
+func TestEngine(t *testing.T) {
+	expect := ttesting.NewExpect(t)
+
+	engine, err := LookupEngine("re2")
+	expect.NoError(err)
+	expect.Equal(RE2, engine)
+	_, err = LookupEngine("unknown")
+	expect.NotNil(err)
+
+	g, err := New(Config{
+		NamedCapturesOnly: true,
+		Engine:            RE2,
+		Patterns: map[string]string{
+			"NOTIME": `(?!<[0-9])%{HOUR}:%{MINUTE}`,
+			"LINE":   `%{IP:client} %{NOTIME:time}`,
+		},
+	})
+	expect.NoError(err)
+	expect.Equal(RE2, g.Engine())
+
+	compiled, err := g.Compile(`%{IP:client} \[%{WORD:verb}\]`)
+	expect.NoError(err)
+	matched, values := compiled.MatchAgainst("10.0.0.1 [GET]")
+	expect.True(matched)
+	expect.MapEqual(values, "client", "10.0.0.1")
+	expect.MapEqual(values, "verb", "GET")
+	expect.Equal(2, len(compiled.Fields()))
+
+	_, err = g.Compile(`%{LINE}`)
+	syntaxErr, isUnsupported := err.(*UnsupportedSyntaxError)
+	expect.True(isUnsupported)
+	if isUnsupported {
+		expect.Equal("NOTIME", syntaxErr.Pattern)
+		expect.Equal("lookahead", syntaxErr.Construct)
+	}
+}
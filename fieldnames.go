@@ -0,0 +1,130 @@
+package grok
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// NameNormalizer converts the name of a capture before it is returned,
+// see Config.NormalizeName.
+type NameNormalizer func(name string) string
+
+// SnakeCase converts names like "clientIP" or "client-ip" to "client_ip".
+// Field path separators like in "[client][ip]" are kept.
+func SnakeCase(name string) string {
+	runes := []rune(name)
+	var snake strings.Builder
+	for i, r := range runes {
+		switch {
+		case r == '-' || r == ' ':
+			snake.WriteRune('_')
+
+		case unicode.IsUpper(r):
+			if i > 0 {
+				previous := runes[i-1]
+				nextIsLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+				// Start a new word after a lower case letter or digit and at
+				// the end of an acronym, e.g. "HTTPVersion"
+				if unicode.IsLower(previous) || unicode.IsDigit(previous) || unicode.IsUpper(previous) && nextIsLower {
+					snake.WriteRune('_')
+				}
+			}
+			snake.WriteRune(unicode.ToLower(r))
+
+		default:
+			snake.WriteRune(r)
+		}
+	}
+	return snake.String()
+}
+
+// LowerCase converts names to lower case.
+func LowerCase(name string) string {
+	return strings.ToLower(name)
+}
+
+// PrefixNames returns a NameNormalizer adding a prefix to each name, e.g.
+// "nginx." to nest all fields below "nginx" with MatchAgainstNested.
+func PrefixNames(prefix string) NameNormalizer {
+	return func(name string) string {
+		return prefix + name
+	}
+}
+
+// ChainNormalizers returns a NameNormalizer applying the given normalizers
+// in order.
+func ChainNormalizers(normalizers ...NameNormalizer) NameNormalizer {
+	return func(name string) string {
+		for _, normalize := range normalizers {
+			name = normalize(name)
+		}
+		return name
+	}
+}
+
+// FieldPath splits a field name into its path, e.g. "[client][ip]" and
+// "client.ip" both return ["client", "ip"]. Names without separators are
+// returned as a single element.
+func FieldPath(name string) []string {
+	path := []string{}
+	var segment strings.Builder
+	flush := func() {
+		if segment.Len() > 0 {
+			path = append(path, segment.String())
+			segment.Reset()
+		}
+	}
+
+	inBrackets := false
+	for _, r := range name {
+		switch {
+		case r == '[' && !inBrackets:
+			flush()
+			inBrackets = true
+		case r == ']' && inBrackets:
+			flush()
+			inBrackets = false
+		case r == '.' && !inBrackets:
+			flush()
+		default:
+			segment.WriteRune(r)
+		}
+	}
+	flush()
+	return path
+}
+
+// Nest converts a flat map of values into nested maps by splitting the keys
+// with FieldPath. An error is returned if a field is both a value and the
+// parent of another field, e.g. "client" and "client.ip".
+func Nest(values map[string]interface{}) (map[string]interface{}, error) {
+	nested := make(map[string]interface{}, len(values))
+	for key, value := range values {
+		path := FieldPath(key)
+		if len(path) == 0 {
+			continue
+		}
+
+		parent := nested
+		for _, segment := range path[:len(path)-1] {
+			switch child := parent[segment].(type) {
+			case nil:
+				created := make(map[string]interface{})
+				parent[segment] = created
+				parent = created
+			case map[string]interface{}:
+				parent = child
+			default:
+				return nil, fmt.Errorf("field %s conflicts with the value of %s", key, segment)
+			}
+		}
+
+		last := path[len(path)-1]
+		if _, isMap := parent[last].(map[string]interface{}); isMap {
+			return nil, fmt.Errorf("field %s conflicts with its nested fields", key)
+		}
+		parent[last] = value
+	}
+	return nested, nil
+}
@@ -0,0 +1,79 @@
+package grok
+
+import (
+	"github.com/trivago/tgo/ttesting"
+	"testing"
+)
+
+func TestFieldPath(t *testing.T) {
+	expect := ttesting.NewExpect(t)
+
+	expect.Equal([]string{"client", "ip"}, FieldPath("[client][ip]"))
+	expect.Equal([]string{"client", "ip"}, FieldPath("client.ip"))
+	expect.Equal([]string{"nginx", "client", "ip"}, FieldPath("nginx.[client][ip]"))
+	expect.Equal([]string{"@metadata", "a.b"}, FieldPath("[@metadata][a.b]"))
+	expect.Equal([]string{"clientip"}, FieldPath("clientip"))
+}
+
+func TestAliasWithDash(t *testing.T) {
+	expect := ttesting.NewExpect(t)
+
+	g, err := New(Config{NamedCapturesOnly: true})
+	expect.NoError(err)
+	compiled, err := g.Compile(`%{WORD:src-zone} %{WORD:dst-zone}`)
+	expect.NoError(err)
+	expect.Equal([]string{"src-zone", "dst-zone"}, compiled.FieldNames())
+
+	matched, values := compiled.MatchAgainst("trust untrust")
+	expect.True(matched)
+	expect.Equal("trust", values["src-zone"])
+	expect.Equal("untrust", values["dst-zone"])
+}
+
+func TestNameNormalizers(t *testing.T) {
+	expect := ttesting.NewExpect(t)
+
+	expect.Equal("client_ip", SnakeCase("clientIP"))
+	expect.Equal("client_ip", SnakeCase("client-ip"))
+	expect.Equal("http_version", SnakeCase("HTTPVersion"))
+	expect.Equal("syslog5424_pri", SnakeCase("syslog5424_pri"))
+	expect.Equal("[client][ip_address]", SnakeCase("[client][ipAddress]"))
+	expect.Equal("app.clientip", ChainNormalizers(LowerCase, PrefixNames("app."))("ClientIP"))
+}
+
+func TestMatchAgainstNested(t *testing.T) {
+	expect := ttesting.NewExpect(t)
+
+	g, err := New(Config{NamedCapturesOnly: true})
+	expect.NoError(err)
+	compiled, err := g.Compile(`%{IP:[client][ip]}:%{POSINT:client.port:int} %{WORD:verb}`)
+	expect.NoError(err)
+
+	matched, values, err := compiled.MatchAgainstNested("10.0.0.1:8080 GET")
+	expect.NoError(err)
+	expect.True(matched)
+	expect.Equal(map[string]interface{}{
+		"client": map[string]interface{}{"ip": "10.0.0.1", "port": 8080},
+		"verb":   "GET",
+	}, values)
+
+	compiled, err = g.Compile(`%{WORD:client} %{IP:client.ip}`)
+	expect.NoError(err)
+	_, _, err = compiled.MatchAgainstNested("alice 10.0.0.1")
+	expect.NotNil(err)
+
+	g, err = New(Config{
+		NamedCapturesOnly: true,
+		NormalizeName:     ChainNormalizers(SnakeCase, PrefixNames("http.")),
+		Patterns:          map[string]string{"REQUEST": `%{WORD:requestMethod} %{NUMBER:responseBytes:int}`},
+	})
+	expect.NoError(err)
+	compiled, err = g.Compile(`%{REQUEST}`)
+	expect.NoError(err)
+	matched, values, err = compiled.MatchAgainstNested("GET 512")
+	expect.NoError(err)
+	expect.True(matched)
+	expect.Equal(map[string]interface{}{
+		"http": map[string]interface{}{"request_method": "GET", "response_bytes": 512},
+	}, values)
+}
@@ -0,0 +1,297 @@
+package grok
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// FieldProcessor expands the value of a capture into sub-fields, e.g. the
+// key-value pairs of a message.
+type FieldProcessor func(value string) (map[string]interface{}, error)
+
+// ProcessField applies a FieldProcessor to a field, see ApplyProcessors.
+type ProcessField struct {
+	// Field is the name of the field to expand. Sub-fields created by an
+	// earlier processor can be given as a path, e.g. "sd.meta@1.payload".
+	Field     string
+	Processor FieldProcessor
+	// Target is the name the sub-fields are stored under as a map. It
+	// defaults to Field, i.e. the value is replaced by its sub-fields.
+	Target string
+	// Merge adds the sub-fields to the top level instead of Target. The
+	// field itself is kept.
+	Merge bool
+}
+
+// ApplyProcessors expands the fields of values with the given processors in
+// order. Fields that were not captured or are empty are skipped. An error is
+// returned if a processor fails.
+func ApplyProcessors(values map[string]interface{}, fields ...ProcessField) error {
+	for _, field := range fields {
+		value, found := lookupField(values, field.Field)
+		text, isString := value.(string)
+		if !found || !isString || len(text) == 0 {
+			continue
+		}
+
+		expanded, err := field.Processor(text)
+		if err != nil {
+			return fmt.Errorf("field %s: %s", field.Field, err)
+		}
+
+		if field.Merge {
+			for key, subValue := range expanded {
+				values[key] = subValue
+			}
+			continue
+		}
+		target := field.Target
+		if len(target) == 0 {
+			target = field.Field
+		}
+		if err := storeField(values, target, expanded); err != nil {
+			return fmt.Errorf("field %s: %s", field.Field, err)
+		}
+	}
+	return nil
+}
+
+// MatchAgainstProcessed works like MatchAgainstTyped and expands the
+// matched fields with ApplyProcessors.
+func (compiled CompiledGrok) MatchAgainstProcessed(text string, fields ...ProcessField) (bool, map[string]interface{}, error) {
+	matched, values, err := compiled.MatchAgainstTyped(text)
+	if !matched || err != nil {
+		return matched, values, err
+	}
+
+	err = ApplyProcessors(values, fields...)
+	return err == nil, values, err
+}
+
+// lookupField returns the value of a field name or of a path into the maps
+// created by earlier processors
+func lookupField(values map[string]interface{}, name string) (interface{}, bool) {
+	if value, found := values[name]; found {
+		return value, true
+	}
+
+	path := FieldPath(name)
+	var current interface{} = values
+	for _, segment := range path {
+		parent, isMap := current.(map[string]interface{})
+		if !isMap {
+			return nil, false
+		}
+		child, found := parent[segment]
+		if !found {
+			return nil, false
+		}
+		current = child
+	}
+	return current, len(path) > 0
+}
+
+// storeField sets a field name or a path into the maps created by earlier
+// processors. Existing top level fields are replaced. An error is returned
+// if the name is a path without segments, e.g. "[]".
+func storeField(values map[string]interface{}, name string, value interface{}) error {
+	if _, found := values[name]; found {
+		values[name] = value
+		return nil
+	}
+
+	path := FieldPath(name)
+	if len(path) == 0 {
+		return fmt.Errorf("invalid target %q", name)
+	}
+	parent := values
+	for _, segment := range path[:len(path)-1] {
+		child, isMap := parent[segment].(map[string]interface{})
+		if !isMap {
+			child = make(map[string]interface{})
+			parent[segment] = child
+		}
+		parent = child
+	}
+	parent[path[len(path)-1]] = value
+	return nil
+}
+
+// KeyValueConfig configures the parser returned by KeyValueParser.
+type KeyValueConfig struct {
+	// FieldSplit holds the characters separating pairs, " " by default
+	FieldSplit string
+	// ValueSplit holds the characters separating key and value, "=" by
+	// default
+	ValueSplit string
+	// Quotes holds the characters that can enclose a value. The value ends
+	// at the next unescaped quote of the same kind, so it may contain field
+	// separators. A backslash escapes the next character. `"'` by default.
+	Quotes string
+}
+
+// KeyValueParser returns a FieldProcessor splitting a value into key-value
+// pairs like `src=10.0.0.1 action="Permit all"`. Words without a value
+// separator are ignored and the last value of a repeated key is kept.
+func KeyValueParser(config KeyValueConfig) FieldProcessor {
+	if len(config.FieldSplit) == 0 {
+		config.FieldSplit = " "
+	}
+	if len(config.ValueSplit) == 0 {
+		config.ValueSplit = "="
+	}
+	if len(config.Quotes) == 0 {
+		config.Quotes = `"'`
+	}
+
+	return func(value string) (map[string]interface{}, error) {
+		pairs := make(map[string]interface{})
+		for i := 0; i < len(value); {
+			// Skip separators, then read the key
+			if strings.IndexByte(config.FieldSplit, value[i]) >= 0 {
+				i++
+				continue
+			}
+			start := i
+			for i < len(value) && strings.IndexByte(config.FieldSplit+config.ValueSplit, value[i]) < 0 {
+				i++
+			}
+			key := value[start:i]
+			if i >= len(value) || strings.IndexByte(config.ValueSplit, value[i]) < 0 {
+				continue // no value
+			}
+			i++
+
+			var pairValue string
+			if i < len(value) && strings.IndexByte(config.Quotes, value[i]) >= 0 {
+				pairValue, i, _ = readQuoted(value, i, "")
+			} else {
+				start = i
+				for i < len(value) && strings.IndexByte(config.FieldSplit, value[i]) < 0 {
+					i++
+				}
+				pairValue = value[start:i]
+			}
+			if len(key) > 0 {
+				pairs[key] = pairValue
+			}
+		}
+		return pairs, nil
+	}
+}
+
+// readQuoted returns the unescaped text quoted by the character at start,
+// the offset behind the closing quote and if the quote was closed.
+// Unterminated values end with the text. A backslash escapes the next
+// character if it is one of escapable, or any character if escapable is
+// empty. Other backslashes are kept.
+func readQuoted(text string, start int, escapable string) (string, int, bool) {
+	quote := text[start]
+	unquoted := strings.Builder{}
+	for i := start + 1; i < len(text); i++ {
+		switch text[i] {
+		case '\\':
+			if i+1 < len(text) && (len(escapable) == 0 || strings.IndexByte(escapable, text[i+1]) >= 0) {
+				i++
+			}
+			unquoted.WriteByte(text[i])
+		case quote:
+			return unquoted.String(), i + 1, true
+		default:
+			unquoted.WriteByte(text[i])
+		}
+	}
+	return unquoted.String(), len(text), false
+}
+
+// sdEscapable are the characters escaped in parameter values of structured
+// data, see RFC 5424 section 6.3.3
+const sdEscapable = `"\]`
+
+// ParseStructuredData is a FieldProcessor for the structured data of RFC 5424
+// syslog messages as captured by SYSLOG5424SD, e.g.
+// `[exampleSDID@32473 iut="3" eventSource="Application"]`. Each element is
+// returned as a map of its parameters by its ID. "-" returns no fields.
+func ParseStructuredData(value string) (map[string]interface{}, error) {
+	elements := make(map[string]interface{})
+	if value == "-" {
+		return elements, nil
+	}
+
+	for i := 0; i < len(value); {
+		if value[i] != '[' {
+			return nil, fmt.Errorf("invalid structured data at offset %d: expected [", i)
+		}
+		i++
+
+		start := i
+		for i < len(value) && value[i] != ' ' && value[i] != ']' {
+			i++
+		}
+		id := value[start:i]
+		if len(id) == 0 {
+			return nil, fmt.Errorf("invalid structured data at offset %d: missing element ID", start)
+		}
+
+		params := make(map[string]interface{})
+		for i < len(value) && value[i] == ' ' {
+			i++
+			start = i
+			for i < len(value) && value[i] != '=' {
+				i++
+			}
+			name := value[start:i]
+			if i+1 >= len(value) || value[i+1] != '"' {
+				return nil, fmt.Errorf("invalid structured data at offset %d: expected quoted value of %s", i, name)
+			}
+
+			// Values escape '"', '\' and ']' with a backslash, other
+			// backslashes are kept
+			paramValue, end, closed := readQuoted(value, i+1, sdEscapable)
+			if !closed {
+				return nil, fmt.Errorf("invalid structured data: unterminated value of %s", name)
+			}
+			params[name] = paramValue
+			i = end
+		}
+
+		if i >= len(value) || value[i] != ']' {
+			return nil, fmt.Errorf("invalid structured data at offset %d: expected ]", i)
+		}
+		i++
+		elements[id] = params
+	}
+	return elements, nil
+}
+
+// ParseJSON is a FieldProcessor for values holding a JSON object. Numbers
+// are returned as json.Number.
+func ParseJSON(value string) (map[string]interface{}, error) {
+	decoder := json.NewDecoder(strings.NewReader(value))
+	decoder.UseNumber()
+
+	object := make(map[string]interface{})
+	if err := decoder.Decode(&object); err != nil {
+		return nil, err
+	}
+	if decoder.More() {
+		return nil, fmt.Errorf("unexpected data after JSON object")
+	}
+	return object, nil
+}
+
+// ChainProcessors returns a FieldProcessor trying the given processors in
+// order. The sub-fields of the first one that succeeds are returned.
+func ChainProcessors(processors ...FieldProcessor) FieldProcessor {
+	return func(value string) (map[string]interface{}, error) {
+		var err error
+		for _, processor := range processors {
+			var expanded map[string]interface{}
+			if expanded, err = processor(value); err == nil {
+				return expanded, nil
+			}
+		}
+		return nil, err
+	}
+}
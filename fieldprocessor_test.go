@@ -0,0 +1,124 @@
+package grok
+
+import (
+	"encoding/json"
+	"github.com/trivago/tgo/ttesting"
+	"testing"
+)
+
+func TestKeyValueParser(t *testing.T) {
+	expect := ttesting.NewExpect(t)
+
+	parse := KeyValueParser(KeyValueConfig{})
+	pairs, err := parse(`start_time="2009-03-03 15:06:06" duration=0 policy_id=2 service=syslog src zone=Untrust action=Permit msg='it\'s ok'`)
+	expect.NoError(err)
+	expect.Equal(map[string]interface{}{
+		"start_time": "2009-03-03 15:06:06",
+		"duration":   "0",
+		"policy_id":  "2",
+		"service":    "syslog",
+		"zone":       "Untrust",
+		"action":     "Permit",
+		"msg":        "it's ok",
+	}, pairs)
+
+	parse = KeyValueParser(KeyValueConfig{FieldSplit: ";&", ValueSplit: ":", Quotes: "|"})
+	pairs, err = parse(`a:1;b:|x;y|&c:`)
+	expect.NoError(err)
+	expect.Equal(map[string]interface{}{"a": "1", "b": "x;y", "c": ""}, pairs)
+}
+
+func TestParseStructuredData(t *testing.T) {
+	expect := ttesting.NewExpect(t)
+
+	elements, err := ParseStructuredData(`[exampleSDID@32473 iut="3" eventSource="Application" eventID="1011"][examplePriority@32473 class="high"][meta note="a \"quoted\] value\\"]`)
+	expect.NoError(err)
+	expect.Equal(map[string]interface{}{
+		"exampleSDID@32473": map[string]interface{}{
+			"iut":         "3",
+			"eventSource": "Application",
+			"eventID":     "1011",
+		},
+		"examplePriority@32473": map[string]interface{}{"class": "high"},
+		"meta":                  map[string]interface{}{"note": `a "quoted] value\`},
+	}, elements)
+
+	// Other backslashes are kept
+	elements, err = ParseStructuredData(`[win path="C:\temp\new" end="\n\\"]`)
+	expect.NoError(err)
+	expect.Equal(map[string]interface{}{
+		"win": map[string]interface{}{"path": `C:\temp\new`, "end": `\n\`},
+	}, elements)
+
+	elements, err = ParseStructuredData("-")
+	expect.NoError(err)
+	expect.Equal(0, len(elements))
+
+	for _, invalid := range []string{`x`, `[]`, `[id a=1]`, `[id a="1"`, `[id a="1]`} {
+		_, err = ParseStructuredData(invalid)
+		expect.NotNil(err)
+	}
+}
+
+func TestParseJSON(t *testing.T) {
+	expect := ttesting.NewExpect(t)
+
+	object, err := ParseJSON(`{"user": {"id": 42}, "tags": ["a"]}`)
+	expect.NoError(err)
+	expect.Equal(map[string]interface{}{
+		"user": map[string]interface{}{"id": json.Number("42")},
+		"tags": []interface{}{"a"},
+	}, object)
+
+	_, err = ParseJSON(`["a"]`)
+	expect.NotNil(err)
+	_, err = ParseJSON(`{"a": 1} trailing`)
+	expect.NotNil(err)
+}
+
+func TestMatchAgainstProcessed(t *testing.T) {
+	expect := ttesting.NewExpect(t)
+
+	g, err := New(Config{NamedCapturesOnly: true, Patterns: map[string]string{
+		"SD": `(?:-|(?:\[[^\]]*\])+)`,
+	}})
+	expect.NoError(err)
+	compiled, err := g.Compile(`%{WORD:app} %{SD:sd} %{GREEDYDATA:message}`)
+	expect.NoError(err)
+
+	matched, values, err := compiled.MatchAgainstProcessed(`api [req@1 id="7" body="{\"user\":\"bob\"}"] status=200 bytes=512`,
+		ProcessField{Field: "sd", Processor: ParseStructuredData},
+		ProcessField{Field: "sd.[req@1].body", Processor: ParseJSON},
+		ProcessField{Field: "message", Processor: KeyValueParser(KeyValueConfig{}), Merge: true},
+	)
+	expect.NoError(err)
+	expect.True(matched)
+	expect.Equal(map[string]interface{}{
+		"app": "api",
+		"sd": map[string]interface{}{
+			"req@1": map[string]interface{}{
+				"id":   "7",
+				"body": map[string]interface{}{"user": "bob"},
+			},
+		},
+		"message": "status=200 bytes=512",
+		"status":  "200",
+		"bytes":   "512",
+	}, values)
+
+	_, _, err = compiled.MatchAgainstProcessed(`api - {broken`, ProcessField{Field: "message", Processor: ParseJSON, Target: "payload"})
+	expect.NotNil(err)
+
+	matched, values, err = compiled.MatchAgainstProcessed(`api - {"a":"b"}`,
+		ProcessField{Field: "message", Processor: ChainProcessors(ParseJSON, KeyValueParser(KeyValueConfig{})), Target: "payload"})
+	expect.NoError(err)
+	expect.True(matched)
+	expect.Equal(map[string]interface{}{"a": "b"}, values["payload"])
+	expect.Equal(`{"a":"b"}`, values["message"])
+
+	// Targets must name a field
+	for _, target := range []string{".", "[]"} {
+		_, _, err = compiled.MatchAgainstProcessed(`api - {"a":"b"}`, ProcessField{Field: "message", Processor: ParseJSON, Target: target})
+		expect.NotNil(err)
+	}
+}
@@ -0,0 +1,46 @@
+//go:build go1.18 && cgo && !nopcre
+// +build go1.18,cgo,!nopcre
+
+package grok
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/rtkjweeks/go-pcre"
+)
+
+func FuzzFindAllSubstring(f *testing.F) {
+	f.Add(namedReference.String(), "%{IP:client} %{WORD}")
+	f.Add(replacementReference.String(), "(?<a>x)(?<b-c>y)")
+	for _, expression := range DefaultPatterns {
+		f.Add(namedReference.String(), expression)
+	}
+
+	f.Fuzz(func(t *testing.T, expression, subject string) {
+		re, err := pcre.Compile(expression, 0)
+		if err != nil {
+			return
+		}
+		matches, err := FindAllSubstring(re, subject, 0)
+		if err != nil {
+			return
+		}
+
+		for _, match := range matches {
+			if !strings.Contains(subject, match.FullTag) || !strings.Contains(match.FullTag, match.NameAndAlias) {
+				t.Errorf("%+v is not part of %q", match, subject)
+			}
+		}
+
+		// The references found with PCRE and with the regexp package must
+		// be the same.
+		if expression == namedReference.String() {
+			expected := findReferences(namedReference, subject)
+			if !reflect.DeepEqual(expected, matches) {
+				t.Errorf("expected %+v, got %+v", expected, matches)
+			}
+		}
+	})
+}
@@ -0,0 +1,151 @@
+//go:build go1.18
+// +build go1.18
+
+package grok
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/rtkjweeks/grok-go-pcre/patterns"
+)
+
+// Run a target with e.g. go test -run=^$ -fuzz=FuzzAddList
+// Inputs that failed before are kept in testdata/fuzz and run by go test.
+
+// addPatternSeeds adds the definition of every default and bundled pattern
+// to the seed corpus.
+func addPatternSeeds(f *testing.F) {
+	for _, expression := range DefaultPatterns {
+		f.Add(expression)
+	}
+	for _, pack := range patterns.Packs {
+		for _, expression := range pack {
+			f.Add(expression)
+		}
+	}
+}
+
+// allPatterns merges all bundled packs into a single map
+func allPatterns() map[string]string {
+	merged := make(map[string]string)
+	for _, pack := range patterns.Packs {
+		for name, expression := range pack {
+			merged[name] = expression
+		}
+	}
+	return merged
+}
+
+func FuzzNewPattern(f *testing.F) {
+	known := patternMap{}
+	if err := known.addList(DefaultPatterns, false); err != nil {
+		f.Fatal(err)
+	}
+	addPatternSeeds(f)
+
+	f.Fuzz(func(t *testing.T, expression string) {
+		pattern, err := newPattern(expression, known, false)
+		if err != nil {
+			return
+		}
+
+		// Each named group of the expression has a generated name which
+		// maps back to the name at the same position in the origin.
+		generated := replacementReference.FindAllStringSubmatch(pattern.expression, -1)
+		original := replacementReference.FindAllStringSubmatch(pattern.origin, -1)
+		if len(generated) != len(original) || len(generated) != len(pattern.aliasMap) {
+			t.Fatalf("%d generated names, %d original names and %d aliases", len(generated), len(original), len(pattern.aliasMap))
+		}
+		for i, group := range generated {
+			if alias := pattern.aliasMap[group[1]]; alias != original[i][1] {
+				t.Errorf("group %d is named %s, which maps to %s instead of %s", i, group[1], alias, original[i][1])
+			}
+		}
+	})
+}
+
+func FuzzAddList(f *testing.F) {
+	for _, pack := range patterns.Packs {
+		names := make([]string, 0, len(pack))
+		for name := range pack {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		var definitions bytes.Buffer
+		for _, name := range names {
+			fmt.Fprintf(&definitions, "%s %s\n", name, pack[name])
+		}
+		f.Add(definitions.String())
+	}
+
+	f.Fuzz(func(t *testing.T, definitions string) {
+		newPatterns, err := ReadPatterns(strings.NewReader(definitions))
+		if err != nil {
+			return
+		}
+
+		known := patternMap{}
+		if err := known.addList(newPatterns, false); err != nil {
+			return
+		}
+		for name := range newPatterns {
+			if _, added := known[name]; !added {
+				t.Errorf("%s was not added", name)
+			}
+		}
+	})
+}
+
+func FuzzMatch(f *testing.F) {
+	g, err := New(Config{Patterns: allPatterns()})
+	if err != nil {
+		f.Fatal(err)
+	}
+
+	for pack := range patterns.Packs {
+		data, err := patterns.Corpus(pack)
+		if err != nil {
+			f.Fatal(err)
+		}
+		corpus, err := ReadCorpus(bytes.NewReader(data))
+		if err != nil {
+			f.Fatal(err)
+		}
+		for _, sample := range corpus {
+			f.Add("%{"+sample.Pattern+"}", sample.Input)
+		}
+	}
+
+	f.Fuzz(func(t *testing.T, expression, input string) {
+		compiled, err := g.Compile(expression)
+		if err != nil {
+			return
+		}
+		if len(compiled.groupIdToName) != compiled.regexp.Groups()+1 {
+			t.Fatalf("%d group names for %d groups", len(compiled.groupIdToName), compiled.regexp.Groups())
+		}
+
+		matched := compiled.MatchString(input)
+		if compiled.Match([]byte(input)) != matched {
+			t.Errorf("Match and MatchString disagree on %q", input)
+		}
+
+		matchedAgainst, values := compiled.MatchAgainst(input)
+		if matchedAgainst != matched {
+			t.Errorf("MatchAgainst and MatchString disagree on %q", input)
+		}
+		for name, value := range values {
+			if !strings.Contains(input, value) {
+				t.Errorf("field %s captured %q, which is not part of %q", name, value, input)
+			}
+		}
+
+		compiled.MatchAgainstTyped(input)
+		compiled.Fields()
+	})
+}
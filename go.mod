@@ -1,3 +1,5 @@
 module github.com/rtkjweeks/grok-go-pcre
 
 go 1.17
+
+require gopkg.in/yaml.v2 v2.4.0
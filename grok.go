@@ -2,7 +2,7 @@ package grok
 
 import (
 	"fmt"
-	"github.com/rtkjweeks/go-pcre"
+	"strings"
 )
 
 // Config is used to pass a set of configuration values to the grok.New function.
@@ -11,6 +11,20 @@ type Config struct {
 	SkipDefaultPatterns bool
 	RemoveEmptyValues   bool
 	Patterns            map[string]string
+	// Engine compiles the expanded expressions. DefaultEngine is used if
+	// no engine is set.
+	Engine Engine
+	// NormalizeName converts capture names before they are returned, e.g.
+	// SnakeCase. Names are returned as written if it is not set.
+	NormalizeName NameNormalizer
+	// ECSCompatibility replaces DefaultPatterns with ECSDefaultPatterns, so
+	// that fields are named after the Elastic Common Schema, e.g.
+	// "source.address" instead of "clientip". Use the ECS packs of the
+	// patterns package for additional patterns.
+	ECSCompatibility bool
+	// CollectStats records match counts, latencies and empty fields for
+	// each compiled expression, see CompiledGrok.Stats.
+	CollectStats bool
 }
 
 // Grok holds a cache of known pattern substitions and acts as a builder for
@@ -20,6 +34,9 @@ type Grok struct {
 	patterns    patternMap
 	removeEmpty bool
 	namedOnly   bool
+	engine      Engine
+	normalize   NameNormalizer
+	stats       bool
 }
 
 // New returns a Grok object that caches a given set of patterns and creates
@@ -30,7 +47,7 @@ func New(config Config) (*Grok, error) {
 
 	if !config.SkipDefaultPatterns {
 		// Add default patterns first so they can be referenced later
-		if err := patterns.addList(DefaultPatterns, config.NamedCapturesOnly); err != nil {
+		if err := patterns.addList(config.defaultPatterns(), config.NamedCapturesOnly); err != nil {
 			return nil, err
 		}
 	}
@@ -40,15 +57,42 @@ func New(config Config) (*Grok, error) {
 		return nil, err
 	}
 
-	fmt.Println("Constructing grok")
+	engine := config.Engine
+	if engine == nil {
+		engine = DefaultEngine
+	}
 
 	return &Grok{
 		patterns:    patterns,
 		namedOnly:   config.NamedCapturesOnly,
 		removeEmpty: config.RemoveEmptyValues,
+		engine:      engine,
+		normalize:   config.NormalizeName,
+		stats:       config.CollectStats,
 	}, nil
 }
 
+// defaultPatterns returns the patterns added unless SkipDefaultPatterns is
+// set
+func (config Config) defaultPatterns() map[string]string {
+	if config.ECSCompatibility {
+		return ECSDefaultPatterns
+	}
+	return DefaultPatterns
+}
+
+// withOverrides returns a copy of the config in which the given definitions
+// replace the default patterns and Patterns of the same name
+func (config Config) withOverrides(overrides map[string]string) Config {
+	definitions := configDefinitions(config)
+	for name, definition := range overrides {
+		definitions[name] = definition
+	}
+	config.Patterns = definitions
+	config.SkipDefaultPatterns = true
+	return config
+}
+
 // Compile precompiles a given grok expression. This function should be used
 // when a grok expression is used more than once.
 func (grok Grok) Compile(pattern string) (*CompiledGrok, error) {
@@ -57,12 +101,18 @@ func (grok Grok) Compile(pattern string) (*CompiledGrok, error) {
 		return nil, err
 	}
 
-	// JJW: TODO: No flags for now; do we need any?
-	compiled, err := pcre.Compile(grokPattern.expression, 0)
+	compiled, err := grok.engine.Compile(grokPattern.expression)
 	if err != nil {
+		if unsupported, isUnsupported := err.(*UnsupportedSyntaxError); isUnsupported {
+			unsupported.Pattern = grok.findUnsupported(pattern, unsupported.Construct, map[string]bool{})
+		}
 		return nil, err
 	}
 
+	if grok.normalize != nil {
+		grokPattern.normalizeNames(grok.normalize)
+	}
+
 	numGroups := compiled.Groups()
 	groupIdToName := make([]string, numGroups+1)
 
@@ -70,22 +120,53 @@ func (grok Grok) Compile(pattern string) (*CompiledGrok, error) {
 	// map them back to names (After we perform a match, we iterate/lookup
 	// results by capture group ID, and then use this to map them back to names)
 	for k, v := range grokPattern.aliasMap {
-		groupId, err := compiled.GroupNameToIndex(k);
-		if err == nil {
-			fmt.Printf("  group %s (%s) -> %d\n", k, v, groupId)
+		groupId := compiled.GroupIndex(k)
+		if groupId >= 0 {
 			groupIdToName[groupId] = v
-		} else {
-			fmt.Printf("  group %s (%s) not found!", k, v)
 		}
 	}
 
+	// Named groups are numbered in the order of the references they were
+	// created for, see newPattern
+	groupIdToPattern := make([]string, numGroups+1)
+	for i, name := range grok.capturePatterns(pattern, nil) {
+		if groupId := compiled.GroupIndex(fmt.Sprintf("name%d", i)); groupId >= 0 {
+			groupIdToPattern[groupId] = name
+		}
+	}
 
-	return &CompiledGrok{
-		regexp:        compiled,
-		typeHints:     grokPattern.typeHints,
-		removeEmpty:   grok.removeEmpty,
-		groupIdToName: groupIdToName,
-	}, nil
+	compiledGrok := &CompiledGrok{
+		pattern:          grokPattern,
+		regexp:           compiled,
+		typeHints:        grokPattern.typeHints,
+		removeEmpty:      grok.removeEmpty,
+		groupIdToName:    groupIdToName,
+		groupIdToPattern: groupIdToPattern,
+	}
+	if grok.stats {
+		compiledGrok.stats = newStatsCollector(compiledGrok.FieldNames())
+	}
+	return compiledGrok, nil
+}
+
+// capturePatterns appends the name of the pattern each named group of the
+// expanded expression is created for, in the order of the groups. Groups
+// written as (?<name>...) have no pattern and are added as empty names.
+func (grok Grok) capturePatterns(expression string, patterns []string) []string {
+	for _, match := range captureReference.FindAllStringSubmatch(expression, -1) {
+		if len(match[1]) == 0 {
+			patterns = append(patterns, "")
+			continue
+		}
+		names := strings.Split(match[1], ":")
+		if !grok.namedOnly || len(names) > 1 {
+			patterns = append(patterns, names[0])
+		}
+		if referenced, known := grok.patterns[names[0]]; known {
+			patterns = grok.capturePatterns(referenced.definition, patterns)
+		}
+	}
+	return patterns
 }
 
 // Match returns true if the given data matches the pattern.
@@ -111,3 +192,33 @@ func (grok Grok) MatchString(pattern, text string) (bool, error) {
 
 	return complied.MatchString(text), nil
 }
+
+// Engine returns the engine used to compile expressions.
+func (grok Grok) Engine() Engine {
+	return grok.engine
+}
+
+// findUnsupported returns the name of the first pattern referenced by the
+// given expression whose own definition uses the given unsupported
+// construct. An empty string is returned if the expression itself uses it.
+func (grok Grok) findUnsupported(expression, construct string, visited map[string]bool) string {
+	for _, match := range findReferences(namedReference, expression) {
+		name := strings.Split(match.NameAndAlias, ":")[0]
+		pattern, known := grok.patterns[name]
+		if !known || visited[name] {
+			continue
+		}
+		visited[name] = true
+
+		if found := grok.findUnsupported(pattern.definition, construct, visited); len(found) > 0 {
+			return found
+		}
+		stripped := namedReference.ReplaceAllString(pattern.definition, "")
+		if _, err := grok.engine.Compile(stripped); err != nil {
+			if unsupported, isUnsupported := err.(*UnsupportedSyntaxError); isUnsupported && unsupported.Construct == construct {
+				return name
+			}
+		}
+	}
+	return ""
+}
@@ -3,74 +3,86 @@ package grok
 import (
 	"fmt"
 	"os"
+	"regexp"
 	"strings"
-	"github.com/rtkjweeks/go-pcre"
 )
 
 type grokPattern struct {
+	definition string
 	origin     string
 	expression string
 	typeHints  typeHintByKey
 	aliasMap   map[string]string
 }
 
+// GrokReplacementMatch is a reference found in a grok expression.
+type GrokReplacementMatch struct {
+	NameAndAlias string
+	FullTag string
+}
+
 var (
-	namedReference = pcre.MustCompile(`%{(\w+(?::\w+(?::\w+)?)?)}`, 0)
-	replacementReference = pcre.MustCompile(`\(\?\<(\w+)\>`, 0)
+	// Aliases may be field paths like [client][ip] or client.ip
+	namedReference = regexp.MustCompile(`%{(\w+(?::[\w.\[\]@-]+(?::\w+)?)?)}`)
+	replacementReference = regexp.MustCompile(`\(\?<([\w.\[\]@-]+)>`)
+	// captureReference matches both kinds of references in the order they
+	// are turned into named groups
+	captureReference = regexp.MustCompile(namedReference.String() + "|" + replacementReference.String())
 )
 
+// findReferences returns all matches of re in subject. NameAndAlias holds the
+// first capture of the match, FullTag the whole match.
+func findReferences(re *regexp.Regexp, subject string) []GrokReplacementMatch {
+	found := re.FindAllStringSubmatch(subject, -1)
+	matches := make([]GrokReplacementMatch, 0, len(found))
+	for _, match := range found {
+		matches = append(matches, GrokReplacementMatch{match[1], match[0]})
+	}
+	return matches
+}
+
 func newPattern(pattern string, knownPatterns patternMap, namedOnly bool) (*grokPattern, error) {
 	aliases := newAliasMap()
 	typeHints := typeHintByKey{}
+	definition := pattern
+
+	matches := findReferences(namedReference, pattern)
+	for i := 0; i < len(matches); i++ {
+		names := strings.Split(matches[i].NameAndAlias, ":")
+		refKey, refAlias := names[0], names[0]
+		if len(names) > 1 {
+			refAlias = names[1]
+		}
 
-	fmt.Printf("newPattern %s\n", pattern)
-
-	matches, err := FindAllSubstring(namedReference, pattern, 0)
-	if err == nil {
-		for i := 0; i < len(matches); i++ {
-			names := strings.Split(matches[i].NameAndAlias, ":")
-			refKey, refAlias := names[0], names[0]
-			if len(names) > 1 {
-				refAlias = names[1]
-			}
-
-			key := matches[i].FullTag
-
-			fmt.Printf("pattern is now %s\n", pattern)
-			fmt.Printf("%s %s %s\n", refKey, refAlias, key)
+		key := matches[i].FullTag
 
-			// Add type cast information only if type set, and not string
-			if len(names) == 3 {
-				if names[2] != "string" {
-					typeHints[refAlias] = names[2]
-				}
+		// Add type cast information only if type set, and not string
+		if len(names) == 3 {
+			if names[2] != "string" {
+				typeHints[refAlias] = names[2]
 			}
+		}
 
-			refPattern, patternExists := knownPatterns[refKey]
-			if !patternExists {
-				return nil, fmt.Errorf("no pattern found for %%{%s}", refKey)
-			} else {
-				fmt.Printf("Using patthern '%s' -> %s\n", refKey, refPattern)
-			}
+		refPattern, patternExists := knownPatterns[refKey]
+		if !patternExists {
+			return nil, fmt.Errorf("no pattern found for %%{%s}", refKey)
+		}
 
-			var refExpression string
-			if !namedOnly || (namedOnly && len(names) > 1) {
-				refExpression = fmt.Sprintf("(?<%s>%s)", refAlias, refPattern.origin)
-			} else {
-				refExpression = fmt.Sprintf("(%s)", refPattern.origin)
-			}
+		var refExpression string
+		if !namedOnly || (namedOnly && len(names) > 1) {
+			refExpression = fmt.Sprintf("(?<%s>%s)", refAlias, refPattern.origin)
+		} else {
+			refExpression = fmt.Sprintf("(%s)", refPattern.origin)
+		}
 
-			// Add new type Informations
-			for key, typeName := range refPattern.typeHints {
-				if _, hasTypeHint := typeHints[key]; !hasTypeHint {
-					typeHints[key] = strings.ToLower(typeName)
-				}
+		// Add new type Informations
+		for key, typeName := range refPattern.typeHints {
+			if _, hasTypeHint := typeHints[key]; !hasTypeHint {
+				typeHints[key] = strings.ToLower(typeName)
 			}
-
-			pattern = strings.Replace(pattern, key, refExpression, -1)
 		}
-	} else {
-		return nil, err
+
+		pattern = strings.Replace(pattern, key, refExpression, -1)
 	}
 
 	// We've now converted from grok syntax, to a (mostly) valid regex with original names.
@@ -97,20 +109,33 @@ func newPattern(pattern string, knownPatterns patternMap, namedOnly bool) (*grok
 	// there could be duplicate group names, due to how the substitutions are done, and there could be names that
 	// aren't valid to the regex compiler; so we replace them with "name\d+" patterns, and keep a mapping of these
 	// back to their original names.
-	newMatches, newErr := FindAllSubstring(replacementReference, pattern, 0)
-	if newErr == nil {
-		for i := 0; i < len(newMatches); i++ {
-			name := newMatches[i].NameAndAlias
-			uniqueName := aliases.GetUniqueName(name)
-
-			pattern = strings.Replace(pattern, fmt.Sprintf("(?<%s>", name), fmt.Sprintf("(?<%s>", uniqueName), 1)
-		}
-	}
+	// This is done in a single pass, as a name given by the developer could
+	// equal one of the generated names.
+	pattern = replacementReference.ReplaceAllStringFunc(pattern, func(group string) string {
+		name := group[3 : len(group)-1]
+		return fmt.Sprintf("(?<%s>", aliases.GetUniqueName(name))
+	})
 
 	return &grokPattern{
+		definition: definition,
 		origin:     origin,
 		expression: pattern,
 		typeHints:  typeHints,
 		aliasMap:   aliases.GetMapping(),
 	}, nil
 }
+
+// normalizeNames converts the original capture names and the keys of the
+// type hints with the given normalizer
+func (pattern *grokPattern) normalizeNames(normalize NameNormalizer) {
+	aliases := make(map[string]string, len(pattern.aliasMap))
+	for group, name := range pattern.aliasMap {
+		aliases[group] = normalize(name)
+	}
+	typeHints := make(typeHintByKey, len(pattern.typeHints))
+	for name, typeName := range pattern.typeHints {
+		typeHints[normalize(name)] = typeName
+	}
+	pattern.aliasMap = aliases
+	pattern.typeHints = typeHints
+}
@@ -0,0 +1,167 @@
+package grok
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Field describes a named capture of a compiled grok expression.
+type Field struct {
+	// Name is the name given in the grok expression, e.g. "clientip".
+	Name string `json:"name"`
+	// Group is the unique name of the capture group in the expanded
+	// expression, e.g. "name3".
+	Group string `json:"group"`
+	// Index is the number of the capture group in the expanded expression.
+	Index int `json:"index"`
+	// Type is the type hint of the field or empty if there is none.
+	Type string `json:"type,omitempty"`
+}
+
+// DependencyGraph maps each pattern name to the sorted names of the
+// patterns it references directly.
+type DependencyGraph map[string][]string
+
+// String returns the grok expression this object was compiled from.
+func (compiled CompiledGrok) String() string {
+	return compiled.pattern.definition
+}
+
+// Expanded returns the PCRE expression this object was compiled from, i.e.
+// the grok expression with all %{...} references replaced.
+func (compiled CompiledGrok) Expanded() string {
+	return compiled.pattern.expression
+}
+
+// Fields returns all named captures of the expression ordered by their
+// group index. A name can appear more than once if it is used in different
+// parts of the expression.
+func (compiled CompiledGrok) Fields() []Field {
+	fields := make([]Field, 0, len(compiled.pattern.aliasMap))
+	for group, name := range compiled.pattern.aliasMap {
+		index := compiled.regexp.GroupIndex(group)
+		if index < 0 {
+			continue
+		}
+		fields = append(fields, Field{
+			Name:  name,
+			Group: group,
+			Index: index,
+			Type:  compiled.typeHints[name],
+		})
+	}
+
+	sort.Slice(fields, func(i, j int) bool {
+		return fields[i].Index < fields[j].Index
+	})
+	return fields
+}
+
+// Expand returns the PCRE expression a grok expression is expanded to,
+// without compiling it.
+func (grok Grok) Expand(pattern string) (string, error) {
+	grokPattern, err := newPattern(pattern, grok.patterns, grok.namedOnly)
+	if err != nil {
+		return "", err
+	}
+	return grokPattern.expression, nil
+}
+
+// Definition returns the definition of a known pattern as it was passed to
+// New, i.e. before references were replaced.
+func (grok Grok) Definition(name string) (string, bool) {
+	pattern, known := grok.patterns[name]
+	if !known {
+		return "", false
+	}
+	return pattern.definition, true
+}
+
+// PatternNames returns the sorted names of all known patterns.
+func (grok Grok) PatternNames() []string {
+	names := make([]string, 0, len(grok.patterns))
+	for name := range grok.patterns {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Dependencies returns which known patterns reference which other patterns.
+func (grok Grok) Dependencies() DependencyGraph {
+	graph := make(DependencyGraph, len(grok.patterns))
+	for name, pattern := range grok.patterns {
+		graph[name] = referencedPatterns(pattern.definition)
+	}
+	return graph
+}
+
+// Dependents returns the sorted names of the patterns that reference the
+// given pattern directly.
+func (graph DependencyGraph) Dependents(name string) []string {
+	dependents := []string{}
+	for dependent, references := range graph {
+		for _, reference := range references {
+			if reference == name {
+				dependents = append(dependents, dependent)
+				break
+			}
+		}
+	}
+	sort.Strings(dependents)
+	return dependents
+}
+
+// WriteDOT writes the graph in the graphviz DOT format. Edges point from a
+// pattern to the patterns it references.
+func (graph DependencyGraph) WriteDOT(out io.Writer) error {
+	names := make([]string, 0, len(graph))
+	for name := range graph {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var dot strings.Builder
+	dot.WriteString("digraph grok {\n")
+	for _, name := range names {
+		if len(graph[name]) == 0 {
+			fmt.Fprintf(&dot, "\t%q;\n", name)
+		}
+		for _, reference := range graph[name] {
+			fmt.Fprintf(&dot, "\t%q -> %q;\n", name, reference)
+		}
+	}
+	dot.WriteString("}\n")
+
+	_, err := io.WriteString(out, dot.String())
+	return err
+}
+
+// WriteJSON writes the graph as a JSON object mapping each pattern name to
+// the list of patterns it references.
+func (graph DependencyGraph) WriteJSON(out io.Writer) error {
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(graph)
+}
+
+// referencedPatterns returns the sorted, distinct pattern names referenced by
+// a grok expression.
+func referencedPatterns(expression string) []string {
+	matches := findReferences(namedReference, expression)
+	known := make(map[string]bool, len(matches))
+	references := make([]string, 0, len(matches))
+
+	for _, match := range matches {
+		name := strings.Split(match.NameAndAlias, ":")[0]
+		if !known[name] {
+			known[name] = true
+			references = append(references, name)
+		}
+	}
+	sort.Strings(references)
+	return references
+}
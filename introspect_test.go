@@ -0,0 +1,56 @@
+package grok
+
+import (
+	"bytes"
+	"github.com/trivago/tgo/ttesting"
+	"strings"
+	"testing"
+)
+
+func TestCompiledIntrospection(t *testing.T) {
+	expect := ttesting.NewExpect(t)
+
+	g, err := New(Config{NamedCapturesOnly: true})
+	expect.NoError(err)
+
+	c, err := g.Compile("%{IPV4:ip} %{NUMBER:status:int} %{WORD}")
+	expect.NoError(err)
+	expect.Equal("%{IPV4:ip} %{NUMBER:status:int} %{WORD}", c.String())
+	expect.True(strings.HasPrefix(c.Expanded(), "(?<name0>(?:(?:25[0-5]"))
+
+	expanded, err := g.Expand("%{IPV4:ip} %{NUMBER:status:int} %{WORD}")
+	expect.NoError(err)
+	expect.Equal(c.Expanded(), expanded)
+
+	fields := c.Fields()
+	expect.Equal(2, len(fields))
+	expect.Equal(Field{Name: "ip", Group: "name0", Index: 1}, fields[0])
+	expect.Equal("status", fields[1].Name)
+	expect.Equal("int", fields[1].Type)
+}
+
+func TestDependencies(t *testing.T) {
+	expect := ttesting.NewExpect(t)
+
+	g, err := New(Config{SkipDefaultPatterns: true, Patterns: map[string]string{
+		"A": "a",
+		"B": "%{A}b%{A:x}",
+		"C": "%{B} %{A}",
+	}})
+	expect.NoError(err)
+
+	graph := g.Dependencies()
+	expect.Equal(0, len(graph["A"]))
+	expect.Equal([]string{"A"}, graph["B"])
+	expect.Equal([]string{"A", "B"}, graph["C"])
+	expect.Equal([]string{"B", "C"}, graph.Dependents("A"))
+
+	dot := bytes.Buffer{}
+	expect.NoError(graph.WriteDOT(&dot))
+	expect.Equal("digraph grok {\n\t\"A\";\n\t\"B\" -> \"A\";\n\t\"C\" -> \"A\";\n\t\"C\" -> \"B\";\n}\n", dot.String())
+
+	definition, known := g.Definition("C")
+	expect.True(known)
+	expect.Equal("%{B} %{A}", definition)
+	expect.Equal([]string{"A", "B", "C"}, g.PatternNames())
+}
@@ -0,0 +1,236 @@
+package grok
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// LintSeverity classifies the issues reported by Lint.
+type LintSeverity int
+
+const (
+	// LintInfo marks issues that are often intended, e.g. top level patterns
+	// that are not referenced by other patterns.
+	LintInfo = LintSeverity(iota)
+	// LintWarning marks issues that likely lead to unexpected results.
+	LintWarning
+	// LintError marks issues that make New fail.
+	LintError
+)
+
+// Names of the checks done by Lint
+const (
+	LintUnreferenced = "unreferenced"
+	LintUndefined    = "undefined"
+	LintShadowed     = "shadowed"
+	LintGreedyData   = "greedydata"
+	LintBacktracking = "backtracking"
+	LintLookaround   = "lookaround"
+	LintCaptureCase  = "capturecase"
+)
+
+// LintIssue describes a problem found by Lint.
+type LintIssue struct {
+	Pattern  string
+	Check    string
+	Severity LintSeverity
+	Message  string
+}
+
+// malformedLookaround finds lookaheads for '<' that were most likely meant
+// to be lookbehinds, e.g. (?!<[0-9]) instead of (?<![0-9])
+var malformedLookaround = regexp.MustCompile(`\(\?[!=]<[^=!]`)
+
+// String returns a human readable name of the severity.
+func (severity LintSeverity) String() string {
+	switch severity {
+	case LintInfo:
+		return "info"
+	case LintWarning:
+		return "warning"
+	default:
+		return "error"
+	}
+}
+
+// String formats the issue as "PATTERN: severity: message (check)".
+func (issue LintIssue) String() string {
+	return fmt.Sprintf("%s: %s: %s (%s)", issue.Pattern, issue.Severity, issue.Message, issue.Check)
+}
+
+// Lint checks a set of patterns for common mistakes. The patterns are
+// checked as if they were passed to New with the default patterns enabled.
+// Issues are sorted by pattern name and check.
+func Lint(patterns map[string]string) []LintIssue {
+	issues := []LintIssue{}
+	report := func(pattern, check string, severity LintSeverity, format string, args ...interface{}) {
+		issues = append(issues, LintIssue{pattern, check, severity, fmt.Sprintf(format, args...)})
+	}
+
+	referenced := make(map[string]bool)
+	for _, expression := range DefaultPatterns {
+		for _, name := range referencedPatterns(expression) {
+			referenced[name] = true
+		}
+	}
+
+	captureNames := make(map[string]map[string][]string)
+	for name, expression := range patterns {
+		for _, reference := range referencedPatterns(expression) {
+			referenced[reference] = true
+			if _, isDefined := patterns[reference]; !isDefined {
+				if _, isDefault := DefaultPatterns[reference]; !isDefault {
+					report(name, LintUndefined, LintError, "references undefined pattern %%{%s}", reference)
+				}
+			}
+		}
+
+		if defaultExpression, isDefault := DefaultPatterns[name]; isDefault && defaultExpression != expression {
+			report(name, LintShadowed, LintWarning, "differs from the default pattern of the same name, which takes precedence unless SkipDefaultPatterns is set")
+		}
+
+		components := splitComponents(expression)
+		greedy := []string{}
+		for i, component := range components {
+			if i < len(components)-1 && strings.HasPrefix(component, "%{GREEDYDATA") && !isAnchor(components[i+1:]) {
+				greedy = append(greedy, component)
+			}
+		}
+		if len(greedy) > 0 {
+			report(name, LintGreedyData, LintWarning, "%s not at the end of the expression, consider %%{DATA} or a more specific pattern", strings.Join(greedy, ", "))
+		}
+
+		if nested := findNestedQuantifier(components, patterns, 0); len(nested) > 0 {
+			report(name, LintBacktracking, LintWarning, "nested unbounded quantifiers in %s may cause catastrophic backtracking", nested)
+		}
+
+		if loc := malformedLookaround.FindStringIndex(expression); loc != nil {
+			found := expression[loc[0] : loc[1]-1]
+			report(name, LintLookaround, LintWarning, "%s is a lookahead for '<', a lookbehind is written as (?<%s", found, found[2:3])
+		}
+
+		matches := findReferences(namedReference, expression)
+		for _, match := range matches {
+			names := strings.Split(match.NameAndAlias, ":")
+			if len(names) < 2 {
+				continue
+			}
+			folded := strings.ToLower(names[1])
+			if captureNames[folded] == nil {
+				captureNames[folded] = make(map[string][]string)
+			}
+			captureNames[folded][names[1]] = append(captureNames[folded][names[1]], name)
+		}
+	}
+
+	for name := range patterns {
+		if !referenced[name] {
+			report(name, LintUnreferenced, LintInfo, "is not referenced by any other pattern")
+		}
+	}
+
+	for _, spellings := range captureNames {
+		if len(spellings) < 2 {
+			continue
+		}
+		variants := make([]string, 0, len(spellings))
+		for spelling := range spellings {
+			variants = append(variants, spelling)
+		}
+		sort.Strings(variants)
+
+		reported := make(map[string]bool)
+		for _, spelling := range variants {
+			for _, name := range spellings[spelling] {
+				if !reported[name] {
+					reported[name] = true
+					report(name, LintCaptureCase, LintWarning, "capture names %s differ only by case", strings.Join(variants, ", "))
+				}
+			}
+		}
+	}
+
+	sort.SliceStable(issues, func(i, j int) bool {
+		if issues[i].Pattern != issues[j].Pattern {
+			return issues[i].Pattern < issues[j].Pattern
+		}
+		return issues[i].Check < issues[j].Check
+	})
+	return issues
+}
+
+// isAnchor returns true if the given components only close groups or anchor
+// the expression, i.e. nothing is left for GREEDYDATA to give back.
+func isAnchor(components []string) bool {
+	for _, component := range components {
+		if component != "$" && component != `\z` && component != `\Z` {
+			return false
+		}
+	}
+	return true
+}
+
+// findNestedQuantifier returns the first group with an unbounded quantifier
+// that contains another unbounded quantifier, or an empty string.
+// References are followed up to a fixed depth.
+func findNestedQuantifier(components []string, patterns map[string]string, depth int) string {
+	for _, component := range components {
+		body, quantifier := splitQuantifier(component)
+		if !strings.HasPrefix(body, "(") {
+			continue
+		}
+		inner := splitComponents(strings.TrimSuffix(strings.TrimPrefix(body, "("), ")"))
+		if isUnbounded(quantifier) && containsUnbounded(inner, patterns, depth) {
+			return component
+		}
+		if nested := findNestedQuantifier(inner, patterns, depth); len(nested) > 0 {
+			return nested
+		}
+	}
+	return ""
+}
+
+// containsUnbounded returns true if any of the components, or the patterns
+// referenced by them, is repeated without an upper limit.
+func containsUnbounded(components []string, patterns map[string]string, depth int) bool {
+	if depth > 8 {
+		return false
+	}
+	for _, component := range components {
+		body, quantifier := splitQuantifier(component)
+		if isUnbounded(quantifier) {
+			return true
+		}
+		switch {
+		case strings.HasPrefix(body, "("):
+			inner := splitComponents(strings.TrimSuffix(strings.TrimPrefix(body, "("), ")"))
+			if containsUnbounded(inner, patterns, depth) {
+				return true
+			}
+		case strings.HasPrefix(body, "%{"):
+			name := strings.Split(strings.TrimSuffix(body[2:], "}"), ":")[0]
+			expression, isDefined := patterns[name]
+			if !isDefined {
+				expression = DefaultPatterns[name]
+			}
+			if containsUnbounded(splitComponents(expression), patterns, depth+1) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// splitQuantifier splits a component into its body and its quantifier
+func splitQuantifier(component string) (string, string) {
+	body := component[:componentEnd(component, 0)]
+	return body, component[len(body):]
+}
+
+// isUnbounded returns true for quantifiers without upper limit
+func isUnbounded(quantifier string) bool {
+	return strings.HasPrefix(quantifier, "*") || strings.HasPrefix(quantifier, "+") ||
+		strings.HasPrefix(quantifier, "{") && strings.Contains(quantifier, ",}")
+}
@@ -0,0 +1,41 @@
+package grok
+
+import (
+	"github.com/trivago/tgo/ttesting"
+	"testing"
+)
+
+func TestLint(t *testing.T) {
+	expect := ttesting.NewExpect(t)
+
+	issues := Lint(map[string]string{
+		"MYTIME":  `(?!<[0-9])%{HOUR}:%{MINUTE}`,
+		"MYLINE":  `%{MYTIME:Time} %{GREEDYDATA:msg} %{WORD:time}`,
+		"MYLIST":  `(?:%{WORD},?)+`,
+		"MYREF":   `%{UNKNOWN}`,
+		"MONTH":   `\b(?:Jan|Feb)\b`,
+		"MYCLEAN": `%{MYTIME:stamp} %{GREEDYDATA:message}$`,
+	})
+
+	checks := map[string][]string{}
+	for _, issue := range issues {
+		if issue.Severity > LintInfo {
+			checks[issue.Pattern] = append(checks[issue.Pattern], issue.Check)
+		}
+	}
+
+	expect.Equal([]string{LintLookaround}, checks["MYTIME"])
+	expect.Equal([]string{LintCaptureCase, LintGreedyData}, checks["MYLINE"])
+	expect.Equal([]string{LintBacktracking}, checks["MYLIST"])
+	expect.Equal([]string{LintUndefined}, checks["MYREF"])
+	expect.Equal([]string{LintShadowed}, checks["MONTH"])
+	expect.MapNotSet(checks, "MYCLEAN")
+
+	unreferenced := map[string]bool{}
+	for _, issue := range issues {
+		if issue.Check == LintUnreferenced {
+			unreferenced[issue.Pattern] = true
+		}
+	}
+	expect.Equal(map[string]bool{"MYLINE": true, "MYLIST": true, "MYREF": true, "MYCLEAN": true}, unreferenced)
+}
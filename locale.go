@@ -0,0 +1,337 @@
+package grok
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Locale holds the month and day names of a language.
+type Locale struct {
+	// Name is the language code, e.g. "de"
+	Name string
+	// Months holds the accepted names of each month, starting with January
+	Months [12][]string
+	// Days holds the accepted names of each weekday, starting with Sunday
+	// like time.Weekday
+	Days [7][]string
+}
+
+var (
+	// English month and day names
+	English = Locale{
+		Name: "en",
+		Months: [12][]string{
+			{"January", "Jan"}, {"February", "Feb"}, {"March", "Mar"}, {"April", "Apr"},
+			{"May"}, {"June", "Jun"}, {"July", "Jul"}, {"August", "Aug"},
+			{"September", "Sept", "Sep"}, {"October", "Oct"}, {"November", "Nov"}, {"December", "Dec"},
+		},
+		Days: [7][]string{
+			{"Sunday", "Sun"}, {"Monday", "Mon"}, {"Tuesday", "Tues", "Tue"}, {"Wednesday", "Wed"},
+			{"Thursday", "Thurs", "Thur", "Thu"}, {"Friday", "Fri"}, {"Saturday", "Sat"},
+		},
+	}
+
+	// German month and day names, including Austrian variants
+	German = Locale{
+		Name: "de",
+		Months: [12][]string{
+			{"Januar", "Jänner", "Jan", "Jän"}, {"Februar", "Feb"}, {"März", "Mär", "Mrz"}, {"April", "Apr"},
+			{"Mai"}, {"Juni", "Jun"}, {"Juli", "Jul"}, {"August", "Aug"},
+			{"September", "Sept", "Sep"}, {"Oktober", "Okt"}, {"November", "Nov"}, {"Dezember", "Dez"},
+		},
+		Days: [7][]string{
+			{"Sonntag", "So"}, {"Montag", "Mo"}, {"Dienstag", "Di"}, {"Mittwoch", "Mi"},
+			{"Donnerstag", "Do"}, {"Freitag", "Fr"}, {"Samstag", "Sonnabend", "Sa"},
+		},
+	}
+
+	// French month and day names
+	French = Locale{
+		Name: "fr",
+		Months: [12][]string{
+			{"janvier", "janv"}, {"février", "févr", "fév"}, {"mars"}, {"avril", "avr"},
+			{"mai"}, {"juin"}, {"juillet", "juil"}, {"août"},
+			{"septembre", "sept"}, {"octobre", "oct"}, {"novembre", "nov"}, {"décembre", "déc"},
+		},
+		Days: [7][]string{
+			{"dimanche", "dim"}, {"lundi", "lun"}, {"mardi", "mar"}, {"mercredi", "mer"},
+			{"jeudi", "jeu"}, {"vendredi", "ven"}, {"samedi", "sam"},
+		},
+	}
+
+	// Spanish month and day names
+	Spanish = Locale{
+		Name: "es",
+		Months: [12][]string{
+			{"enero", "ene"}, {"febrero", "feb"}, {"marzo", "mar"}, {"abril", "abr"},
+			{"mayo", "may"}, {"junio", "jun"}, {"julio", "jul"}, {"agosto", "ago"},
+			{"septiembre", "setiembre", "sept", "sep", "set"}, {"octubre", "oct"}, {"noviembre", "nov"}, {"diciembre", "dic"},
+		},
+		Days: [7][]string{
+			{"domingo", "dom"}, {"lunes", "lun"}, {"martes", "mar"}, {"miércoles", "mié", "mie"},
+			{"jueves", "jue"}, {"viernes", "vie"}, {"sábado", "sáb", "sab"},
+		},
+	}
+)
+
+// Locales holds all bundled locales by name.
+var Locales = map[string]Locale{
+	English.Name: English,
+	German.Name:  German,
+	French.Name:  French,
+	Spanish.Name: Spanish,
+}
+
+// LookupLocale returns the bundled locale of the given name, e.g. "fr".
+func LookupLocale(name string) (Locale, error) {
+	locale, known := Locales[strings.ToLower(name)]
+	if !known {
+		return Locale{}, fmt.Errorf("unknown locale %s", name)
+	}
+	return locale, nil
+}
+
+// MonthPattern returns an expression matching the month names of the given
+// locales. Names are matched as written in the locale or capitalized, or in
+// any case if caseInsensitive is set.
+func MonthPattern(caseInsensitive bool, locales ...Locale) string {
+	names := []string{}
+	for _, locale := range locales {
+		for _, month := range locale.Months {
+			names = append(names, month...)
+		}
+	}
+	return namePattern(names, caseInsensitive)
+}
+
+// DayPattern returns an expression matching the day names of the given
+// locales, see MonthPattern.
+func DayPattern(caseInsensitive bool, locales ...Locale) string {
+	names := []string{}
+	for _, locale := range locales {
+		for _, day := range locale.Days {
+			names = append(names, day...)
+		}
+	}
+	return namePattern(names, caseInsensitive)
+}
+
+// DatePatterns returns MONTH and DAY patterns matching the names of all
+// given locales, plus a variant for each locale suffixed with its name,
+// e.g. MONTH_DE. As New does not replace DefaultPatterns, merge the result
+// into a copy of DefaultPatterns and set SkipDefaultPatterns to make
+// patterns like SYSLOGTIMESTAMP accept localized names.
+func DatePatterns(caseInsensitive bool, locales ...Locale) map[string]string {
+	patterns := map[string]string{
+		"MONTH": MonthPattern(caseInsensitive, locales...),
+		"DAY":   DayPattern(caseInsensitive, locales...),
+	}
+	for _, locale := range locales {
+		suffix := "_" + strings.ToUpper(locale.Name)
+		patterns["MONTH"+suffix] = MonthPattern(caseInsensitive, locale)
+		patterns["DAY"+suffix] = DayPattern(caseInsensitive, locale)
+	}
+	return patterns
+}
+
+// namePattern returns an expression matching one of the given words
+func namePattern(names []string, caseInsensitive bool) string {
+	known := make(map[string]bool)
+	variants := []string{}
+	add := func(name string) {
+		if !known[name] {
+			known[name] = true
+			variants = append(variants, name)
+		}
+	}
+	for _, name := range names {
+		if caseInsensitive {
+			add(strings.ToLower(name))
+		} else {
+			add(name)
+			add(capitalize(name))
+		}
+	}
+
+	// Longer names first so that e.g. "Sept" is preferred over "Sep"
+	sort.SliceStable(variants, func(i, j int) bool {
+		return utf8.RuneCountInString(variants[i]) > utf8.RuneCountInString(variants[j])
+	})
+
+	alternatives := make([]string, 0, len(variants))
+	for _, name := range variants {
+		alternative := regexp.QuoteMeta(name)
+		if caseInsensitive {
+			alternative = foldNonASCII(name)
+		}
+
+		// \b only knows ASCII word characters, so it cannot be used next
+		// to letters like the "é" in "mié"
+		first, _ := utf8.DecodeRuneInString(name)
+		last, _ := utf8.DecodeLastRuneInString(name)
+		if first < utf8.RuneSelf {
+			alternative = `\b` + alternative
+		}
+		if last < utf8.RuneSelf {
+			alternative += `\b`
+		}
+		alternatives = append(alternatives, alternative)
+	}
+
+	if caseInsensitive {
+		return `(?i:` + strings.Join(alternatives, "|") + `)`
+	}
+	return `(?:` + strings.Join(alternatives, "|") + `)`
+}
+
+// foldNonASCII quotes name and replaces each non ASCII letter with an
+// alternation of its upper and lower case form. PCRE only folds ASCII
+// letters unless it is compiled with Unicode support.
+func foldNonASCII(name string) string {
+	var folded strings.Builder
+	for _, r := range name {
+		if r < utf8.RuneSelf || unicode.ToUpper(r) == unicode.ToLower(r) {
+			folded.WriteString(regexp.QuoteMeta(string(r)))
+			continue
+		}
+		folded.WriteString("(?:" + string(unicode.ToLower(r)) + "|" + string(unicode.ToUpper(r)) + ")")
+	}
+	return folded.String()
+}
+
+// capitalize returns name with an upper case first letter
+func capitalize(name string) string {
+	first, size := utf8.DecodeRuneInString(name)
+	return string(unicode.ToUpper(first)) + name[size:]
+}
+
+// ParseMonth returns the month of a localized month name. The name is
+// compared case insensitive. All bundled locales are used if none are
+// given.
+func ParseMonth(name string, locales ...Locale) (time.Month, error) {
+	if month, found := lookupName(name, monthNames, locales); found {
+		return time.Month(month + 1), nil
+	}
+	return 0, fmt.Errorf("unknown month %s", name)
+}
+
+// ParseWeekday returns the weekday of a localized day name, see ParseMonth.
+func ParseWeekday(name string, locales ...Locale) (time.Weekday, error) {
+	if day, found := lookupName(name, dayNames, locales); found {
+		return time.Weekday(day), nil
+	}
+	return 0, fmt.Errorf("unknown weekday %s", name)
+}
+
+// ParseTimestamp parses a timestamp with localized month and day names.
+// The layout is the one of time.Parse and uses the English names, e.g.
+// "02 Jan 2006 15:04:05" parses "02 Mär 2024 10:00:00" with German.
+// All bundled locales are used if none are given.
+func ParseTimestamp(layout, value string, locales ...Locale) (time.Time, error) {
+	type word struct {
+		start, end int
+		month      int
+		day        int
+	}
+
+	// Find all words that are a month or day name. Some abbreviations are
+	// both, e.g. "mar" in Spanish, so all combinations are tried.
+	words := []word{}
+	start := -1
+	for i, r := range value + " " {
+		if unicode.IsLetter(r) {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+		if start >= 0 {
+			month, isMonth := lookupName(value[start:i], monthNames, locales)
+			day, isDay := lookupName(value[start:i], dayNames, locales)
+			if isMonth || isDay {
+				if !isMonth {
+					month = -1
+				}
+				if !isDay {
+					day = -1
+				}
+				words = append(words, word{start, i, month, day})
+			}
+			start = -1
+		}
+	}
+
+	ambiguous := 0
+	for _, w := range words {
+		if w.month >= 0 && w.day >= 0 {
+			ambiguous++
+		}
+	}
+
+	var err error
+	for combination := 0; combination < 1<<uint(ambiguous); combination++ {
+		var translated strings.Builder
+		end, bit := 0, 0
+		for _, w := range words {
+			useMonth := w.month >= 0
+			if w.month >= 0 && w.day >= 0 {
+				useMonth = combination&(1<<uint(bit)) == 0
+				bit++
+			}
+
+			translated.WriteString(value[end:w.start])
+			if useMonth {
+				translated.WriteString(englishName(time.Month(w.month+1).String(), layout, "January"))
+			} else {
+				translated.WriteString(englishName(time.Weekday(w.day).String(), layout, "Monday"))
+			}
+			end = w.end
+		}
+		translated.WriteString(value[end:])
+
+		var parsed time.Time
+		if parsed, err = time.Parse(layout, translated.String()); err == nil {
+			return parsed, nil
+		}
+	}
+	return time.Time{}, err
+}
+
+// englishName returns the full English name if the layout uses the full
+// name, e.g. "January", or the three letter abbreviation otherwise
+func englishName(name, layout, fullLayout string) string {
+	if strings.Contains(layout, fullLayout) {
+		return name
+	}
+	return name[:3]
+}
+
+func monthNames(locale Locale) [][]string {
+	return locale.Months[:]
+}
+
+func dayNames(locale Locale) [][]string {
+	return locale.Days[:]
+}
+
+// lookupName returns the index of the name list containing name
+func lookupName(name string, names func(Locale) [][]string, locales []Locale) (int, bool) {
+	if len(locales) == 0 {
+		locales = []Locale{English, German, French, Spanish}
+	}
+	for _, locale := range locales {
+		for index, variants := range names(locale) {
+			for _, variant := range variants {
+				if strings.EqualFold(variant, name) {
+					return index, true
+				}
+			}
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,75 @@
+package grok
+
+import (
+	"github.com/trivago/tgo/ttesting"
+	"testing"
+	"time"
+)
+
+func TestDatePatterns(t *testing.T) {
+	expect := ttesting.NewExpect(t)
+
+	expect.Equal(`(?:\bMai\b|\bmai\b)`, MonthPattern(false, Locale{Months: [12][]string{4: {"Mai", "mai"}}}))
+	expect.Equal(`(?i:\bm(?:é|É)r\b)`, MonthPattern(true, Locale{Months: [12][]string{{"Mér"}}}))
+
+	patterns := make(map[string]string)
+	for name, expression := range DefaultPatterns {
+		patterns[name] = expression
+	}
+	for name, expression := range DatePatterns(true, English, French, Spanish) {
+		patterns[name] = expression
+	}
+	g, err := New(Config{SkipDefaultPatterns: true, Patterns: patterns})
+	expect.NoError(err)
+
+	for _, month := range []string{"Jan", "MARCH", "févr", "FÉVRIER", "août", "Sept", "setiembre"} {
+		matched, err := g.MatchString("^%{MONTH}$", month)
+		expect.NoError(err)
+		expect.True(matched)
+	}
+	for _, day := range []string{"Sun", "thursday", "Mardi", "MIÉRCOLES", "mié"} {
+		matched, err := g.MatchString("^%{DAY}$", day)
+		expect.NoError(err)
+		expect.True(matched)
+	}
+
+	matched, err := g.MatchString("^%{MONTH_FR}$", "March")
+	expect.NoError(err)
+	expect.False(matched)
+	matched, err = g.MatchString("^%{SYSLOGTIMESTAMP}$", "févr 14 02:01:37")
+	expect.NoError(err)
+	expect.True(matched)
+}
+
+func TestParseTimestamp(t *testing.T) {
+	expect := ttesting.NewExpect(t)
+
+	month, err := ParseMonth("MÄRZ")
+	expect.NoError(err)
+	expect.Equal(time.March, month)
+	month, err = ParseMonth("août", French)
+	expect.NoError(err)
+	expect.Equal(time.August, month)
+	_, err = ParseMonth("août", German)
+	expect.NotNil(err)
+
+	day, err := ParseWeekday("miércoles")
+	expect.NoError(err)
+	expect.Equal(time.Wednesday, day)
+
+	parsed, err := ParseTimestamp("02 Jan 2006 15:04:05", "14 Okt 2024 10:30:00", German)
+	expect.NoError(err)
+	expect.Equal(time.Date(2024, time.October, 14, 10, 30, 0, 0, time.UTC), parsed)
+
+	// "mar" is Tuesday and March in Spanish
+	parsed, err = ParseTimestamp("Mon, 02 Jan 2006", "mar, 12 mar 2024", Spanish)
+	expect.NoError(err)
+	expect.Equal(time.Date(2024, time.March, 12, 0, 0, 0, 0, time.UTC), parsed)
+
+	parsed, err = ParseTimestamp("Monday 2 January 2006", "jeudi 1 août 2024", French)
+	expect.NoError(err)
+	expect.Equal(time.Date(2024, time.August, 1, 0, 0, 0, 0, time.UTC), parsed)
+
+	_, err = ParseTimestamp("02 Jan 2006", "14 Foo 2024")
+	expect.NotNil(err)
+}
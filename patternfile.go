@@ -0,0 +1,149 @@
+package grok
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ReadPatterns parses patterns in the logstash text format, i.e. one
+// "NAME expression" pair per line. Empty lines and lines starting with '#'
+// are ignored.
+func ReadPatterns(r io.Reader) (map[string]string, error) {
+	lines, err := ReadPatternLines(r)
+	if err != nil {
+		return nil, err
+	}
+
+	patterns := make(map[string]string)
+	for _, line := range lines {
+		if len(line.Name) > 0 {
+			patterns[line.Name] = line.Expression
+		}
+	}
+	return patterns, nil
+}
+
+// PatternLine is a single line of a pattern file in the logstash format.
+type PatternLine struct {
+	// Number is the line number, starting at 1
+	Number int
+	// Name and Expression are set for pattern definitions
+	Name       string
+	Expression string
+	// Comment is the text of a comment line without the leading '#'.
+	// Lines without name and comment are empty.
+	Comment string
+	// IsComment is true for comment lines
+	IsComment bool
+}
+
+// ReadPatternLines parses a pattern file in the logstash format like
+// ReadPatterns, but returns all lines including comments and empty lines
+// in the order they were read.
+func ReadPatternLines(r io.Reader) ([]PatternLine, error) {
+	lines := []PatternLine{}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		// Trailing spaces may be part of the expression, the line ending
+		// has already been removed by the scanner
+		line := strings.TrimLeft(scanner.Text(), " \t")
+		switch {
+		case len(line) == 0:
+			lines = append(lines, PatternLine{Number: lineNum})
+
+		case line[0] == '#':
+			lines = append(lines, PatternLine{Number: lineNum, Comment: line[1:], IsComment: true})
+
+		default:
+			name, expression, err := splitPatternLine(line)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %s", lineNum, err)
+			}
+			lines = append(lines, PatternLine{Number: lineNum, Name: name, Expression: expression})
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return lines, nil
+}
+
+// LoadPatterns reads a logstash pattern file. If path is a directory, all
+// files in that directory are read in lexical order, so definitions in later
+// files replace earlier ones.
+func LoadPatterns(path string) (map[string]string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return loadPatternFile(path)
+	}
+
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+	files := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if !entry.IsDir() && !strings.HasPrefix(entry.Name(), ".") {
+			files = append(files, filepath.Join(path, entry.Name()))
+		}
+	}
+	sort.Strings(files)
+
+	patterns := make(map[string]string)
+	for _, file := range files {
+		filePatterns, err := loadPatternFile(file)
+		if err != nil {
+			return nil, err
+		}
+		for name, expression := range filePatterns {
+			patterns[name] = expression
+		}
+	}
+	return patterns, nil
+}
+
+// loadPatternFile reads a single logstash pattern file
+func loadPatternFile(path string) (map[string]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	patterns, err := ReadPatterns(file)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", path, err)
+	}
+	return patterns, nil
+}
+
+// splitPatternLine splits a "NAME expression" line into its parts
+func splitPatternLine(line string) (string, string, error) {
+	sep := strings.IndexAny(line, " \t")
+	if sep < 0 {
+		return "", "", fmt.Errorf("pattern %s has no expression", line)
+	}
+
+	name := line[:sep]
+	for _, c := range name {
+		if !(c == '_' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z') {
+			return "", "", fmt.Errorf("invalid pattern name %s", name)
+		}
+	}
+	expression := strings.TrimLeft(line[sep:], " \t")
+	if len(strings.TrimSpace(expression)) == 0 {
+		return "", "", fmt.Errorf("pattern %s has no expression", name)
+	}
+	return name, expression, nil
+}
@@ -0,0 +1,48 @@
+package grok
+
+import (
+	"github.com/trivago/tgo/ttesting"
+	"strings"
+	"testing"
+)
+
+func TestReadPatterns(t *testing.T) {
+	expect := ttesting.NewExpect(t)
+
+	patterns, err := ReadPatterns(strings.NewReader(`
+# comment
+IRCUSER \A@(\w+)
+IRCMSG	%{IRCUSER:user} .* : %{GREEDYDATA:message}
+`))
+	expect.NoError(err)
+	expect.Equal(2, len(patterns))
+	expect.MapEqual(patterns, "IRCUSER", `\A@(\w+)`)
+	expect.MapEqual(patterns, "IRCMSG", `%{IRCUSER:user} .* : %{GREEDYDATA:message}`)
+
+	_, err = ReadPatterns(strings.NewReader("NOEXPRESSION"))
+	expect.NotNil(err)
+	_, err = ReadPatterns(strings.NewReader("NOEXPRESSION  \t"))
+	expect.NotNil(err)
+
+	// Trailing spaces are part of the expression, line endings are not
+	patterns, err = ReadPatterns(strings.NewReader("  PROMPT \\$ \r\nEND x\r\n"))
+	expect.NoError(err)
+	expect.MapEqual(patterns, "PROMPT", `\$ `)
+	expect.MapEqual(patterns, "END", "x")
+
+	_, err = ReadPatterns(strings.NewReader("IN-VALID .*"))
+	expect.NotNil(err)
+}
+
+func TestReadPatternLines(t *testing.T) {
+	expect := ttesting.NewExpect(t)
+
+	lines, err := ReadPatternLines(strings.NewReader("# users\nIRCUSER \\A@(\\w+)\n\nIRCMSG %{IRCUSER:user}"))
+	expect.NoError(err)
+	expect.Equal([]PatternLine{
+		{Number: 1, Comment: " users", IsComment: true},
+		{Number: 2, Name: "IRCUSER", Expression: `\A@(\w+)`},
+		{Number: 3},
+		{Number: 4, Name: "IRCMSG", Expression: `%{IRCUSER:user}`},
+	}, lines)
+}
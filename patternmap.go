@@ -8,39 +8,44 @@ import (
 type patternMap map[string]*grokPattern
 
 // resolve references inside a pattern so that all substitutions are added
-// in the correct order.
-func (knownPatterns *patternMap) resolve(key, pattern string, newPatterns map[string]string, namedOnly bool) error {
-	fmt.Printf("Resolve %s -> %s\n", key, pattern)
+// in the correct order. The keys of all patterns currently being resolved
+// are kept in resolving to detect patterns referencing themselves.
+func (knownPatterns *patternMap) resolve(key, pattern string, newPatterns map[string]string, namedOnly bool, resolving map[string]bool) error {
+	resolving[key] = true
+	defer delete(resolving, key)
+
 	// find all grok named references: eg: %{MONTH_NUMBER:month}
-	matches, err := FindAllSubstring(namedReference, pattern, 0)
-	if err == nil {
-		for i := 0; i < len(matches); i++ {
-			names := strings.Split(matches[i].NameAndAlias, ":")
-			refKey := names[0]
-
-			// if we haven't already compiled it, take it from the pattern list and compile it
-			// first because this current pattern refers to it, so we must resolve it first...
-			if _, refKeyCompiled := (*knownPatterns)[refKey]; !refKeyCompiled {
-				refPattern, refKeyFound := newPatterns[refKey]
-				if !refKeyFound {
-					return fmt.Errorf("no pattern found for %%{%s}", refKey)
-				}
-				knownPatterns.resolve(refKey, refPattern, newPatterns, namedOnly)
+	matches := findReferences(namedReference, pattern)
+	for i := 0; i < len(matches); i++ {
+		names := strings.Split(matches[i].NameAndAlias, ":")
+		refKey := names[0]
+
+		// if we haven't already compiled it, take it from the pattern list and compile it
+		// first because this current pattern refers to it, so we must resolve it first...
+		if _, refKeyCompiled := (*knownPatterns)[refKey]; !refKeyCompiled {
+			refPattern, refKeyFound := newPatterns[refKey]
+			if !refKeyFound {
+				return fmt.Errorf("no pattern found for %%{%s}", refKey)
+			}
+			if resolving[refKey] {
+				return fmt.Errorf("recursive reference %%{%s} in pattern %s", refKey, key)
+			}
+			if err := knownPatterns.resolve(refKey, refPattern, newPatterns, namedOnly, resolving); err != nil {
+				return err
 			}
 		}
-	} else {
-		return err
 	}
 	return knownPatterns.add(key, pattern, namedOnly)
 }
 
 // add a list of patterns to the map
 func (knownPatterns *patternMap) addList(newPatterns map[string]string, namedOnly bool) error {
+	resolving := make(map[string]bool)
 	for key, pattern := range newPatterns {
 		if _, alreadyCompiled := (*knownPatterns)[key]; alreadyCompiled {
 			continue
 		}
-		if err := knownPatterns.resolve(key, pattern, newPatterns, namedOnly); err != nil {
+		if err := knownPatterns.resolve(key, pattern, newPatterns, namedOnly, resolving); err != nil {
 			return err
 		}
 	}
@@ -50,7 +55,6 @@ func (knownPatterns *patternMap) addList(newPatterns map[string]string, namedOnl
 
 // add a single pattern to the map
 func (knownPatterns *patternMap) add(name, pattern string, namedOnly bool) error {
-	fmt.Printf("Adding pattern %s -> %s\n", name, pattern)
 	p, err := newPattern(pattern, *knownPatterns, namedOnly)
 	if err != nil {
 		return err
@@ -0,0 +1,31 @@
+package patterns
+
+import (
+	"embed"
+	"fmt"
+)
+
+// corpusFiles holds the sample corpus of each pack, stored as corpus/<pack>.json
+//
+//go:embed corpus/*.json
+var corpusFiles embed.FS
+
+// Corpus returns the samples shipped with the pack of the given name, see
+// Packs. The samples are stored as a JSON array that can be read with
+// grok.ReadCorpus and checked with grok.Verify. Fields are given as captured
+// with NamedCapturesOnly set.
+func Corpus(pack string) ([]byte, error) {
+	if _, known := Packs[pack]; !known {
+		return nil, fmt.Errorf("unknown pattern pack %s", pack)
+	}
+	return corpusFiles.ReadFile("corpus/" + pack + ".json")
+}
+
+// ECSCorpus returns the samples of the ECS variant of a pack, see ECSPacks.
+// They are the samples of Corpus with the fields named as in ECSFields.
+func ECSCorpus(pack string) ([]byte, error) {
+	if _, known := ECSPacks[pack]; !known {
+		return nil, fmt.Errorf("no ECS variant of pattern pack %s", pack)
+	}
+	return corpusFiles.ReadFile("corpus/ecs-" + pack + ".json")
+}
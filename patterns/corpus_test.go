@@ -0,0 +1,78 @@
+package patterns_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/rtkjweeks/grok-go-pcre"
+	"github.com/rtkjweeks/grok-go-pcre/patterns"
+	"github.com/trivago/tgo/ttesting"
+)
+
+func TestCorpus(t *testing.T) {
+	for name, pack := range patterns.Packs {
+		expect := ttesting.NewExpect(t)
+
+		data, err := patterns.Corpus(name)
+		expect.NoError(err)
+		corpus, err := grok.ReadCorpus(bytes.NewReader(data))
+		expect.NoError(err)
+
+		g, err := grok.New(packConfig(grok.DefaultPatterns, pack))
+		expect.NoError(err)
+
+		for _, failure := range grok.Verify(g, corpus) {
+			if _, isUnsupported := failure.Err.(*grok.UnsupportedSyntaxError); isUnsupported {
+				// Patterns using PCRE only constructs cannot be checked with RE2
+				t.Logf("%s: skipped %s", name, failure)
+				continue
+			}
+			t.Errorf("%s: %s", name, failure)
+		}
+
+		// Every pattern of the pack that is not used by another pattern
+		// needs at least one sample.
+		graph := g.Dependencies()
+		for pattern := range pack {
+			if len(graph.Dependents(pattern)) > 0 {
+				continue
+			}
+			if !isCovered(corpus, pattern) {
+				t.Errorf("%s: no sample for %s", name, pattern)
+			}
+		}
+	}
+
+	_, err := patterns.Corpus("unknown")
+	ttesting.NewExpect(t).NotNil(err)
+}
+
+// packConfig returns a configuration in which the definitions of a pack
+// replace the default patterns of the same name, like pattern files do in
+// grok.LoadConfig.
+func packConfig(defaults, pack map[string]string) grok.Config {
+	definitions := make(map[string]string, len(defaults)+len(pack))
+	for name, definition := range defaults {
+		definitions[name] = definition
+	}
+	for name, definition := range pack {
+		definitions[name] = definition
+	}
+	return grok.Config{
+		NamedCapturesOnly:   true,
+		SkipDefaultPatterns: true,
+		Patterns:            definitions,
+	}
+}
+
+func isCovered(corpus grok.Corpus, pattern string) bool {
+	for _, sample := range corpus {
+		if sample.Pattern == pattern ||
+			strings.Contains(sample.Pattern, "%{"+pattern+"}") ||
+			strings.Contains(sample.Pattern, "%{"+pattern+":") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,71 @@
+package patterns_test
+
+import (
+	"bytes"
+	"flag"
+	"sort"
+	"testing"
+
+	"github.com/rtkjweeks/grok-go-pcre"
+	"github.com/rtkjweeks/grok-go-pcre/patterns"
+	"github.com/trivago/tgo/ttesting"
+)
+
+var (
+	differentialInputs = flag.Int("differential.inputs", 200, "number of random inputs per pack for TestEngineDifferences")
+	differentialSeed   = flag.Int64("differential.seed", 1, "seed for the random inputs of TestEngineDifferences")
+)
+
+// TestEngineDifferences matches every pattern of the default patterns and
+// all packs with PCRE and RE2. Differences on the corpus fail the test,
+// differences on random inputs derived from the corpus are logged, as the
+// engines are not expected to agree on every malformed line.
+// Run with -v to see them.
+func TestEngineDifferences(t *testing.T) {
+	pcre, err := grok.LookupEngine("pcre")
+	if err != nil {
+		t.Skip("pcre engine is not available in this build")
+	}
+
+	packs := map[string]map[string]string{"default": grok.DefaultPatterns}
+	for name, pack := range patterns.Packs {
+		packs[name] = pack
+	}
+
+	for name, pack := range packs {
+		expect := ttesting.NewExpect(t)
+
+		inputs := []string{}
+		if data, err := patterns.Corpus(name); err == nil {
+			corpus, err := grok.ReadCorpus(bytes.NewReader(data))
+			expect.NoError(err)
+			inputs = corpus.Inputs()
+		}
+
+		withPCRE, err := grok.New(grok.Config{Engine: pcre, Patterns: pack})
+		expect.NoError(err)
+		withRE2, err := grok.New(grok.Config{Engine: grok.RE2, Patterns: pack})
+		expect.NoError(err)
+
+		expressions := make([]string, 0, len(pack))
+		for pattern := range pack {
+			expressions = append(expressions, "%{"+pattern+"}")
+		}
+		sort.Strings(expressions)
+
+		for _, mismatch := range grok.CompareEngines(withPCRE, withRE2, expressions, inputs) {
+			if _, isUnsupported := mismatch.Err.(*grok.UnsupportedSyntaxError); isUnsupported {
+				t.Logf("%s: skipped %s", name, mismatch)
+				continue
+			}
+			t.Errorf("%s: %s", name, mismatch)
+		}
+
+		random := grok.GenerateInputs(inputs, *differentialInputs, *differentialSeed)
+		for _, mismatch := range grok.CompareEngines(withPCRE, withRE2, expressions, random) {
+			if mismatch.Err == nil {
+				t.Logf("%s: %s", name, mismatch)
+			}
+		}
+	}
+}
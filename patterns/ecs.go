@@ -0,0 +1,186 @@
+package patterns
+
+import (
+	"regexp"
+)
+
+// ECSFields holds the mapping of capture names to fields of the Elastic
+// Common Schema (ECS) for each pack with an ECS variant, see ECSPacks.
+// Patterns of a pack referring to the default patterns also use the
+// mapping of grok.ECSDefaultFields, e.g. for "program" of SYSLOGPROG.
+var ECSFields = map[string]map[string]string{
+	"grok": {
+		"agent":       "user_agent.original",
+		"auth":        "user.name",
+		"bytes":       "http.response.body.bytes",
+		"clientip":    "source.address",
+		"facility":    "log.syslog.facility.code",
+		"httpversion": "http.version",
+		"ident":       "apache.access.user.identity",
+		"logsource":   "host.hostname",
+		"pid":         "process.pid",
+		"port":        "url.port",
+		"priority":    "log.syslog.priority",
+		"program":     "process.name",
+		"rawrequest":  "apache.access.request_line",
+		"referrer":    "http.request.referrer",
+		"request":     "url.original",
+		"response":    "http.response.status_code",
+		"verb":        "http.request.method",
+	},
+
+	"aws": {
+		"agent":                    "user_agent.original",
+		"backend_processing_time":  "aws.elb.backend_processing_time.sec",
+		"backend_response":         "aws.elb.backend.http.response.status_code",
+		"backendip":                "aws.elb.backend.ip",
+		"backendport":              "aws.elb.backend.port",
+		"bucket":                   "aws.s3access.bucket",
+		"bytes":                    "http.response.body.bytes",
+		"clientip":                 "source.ip",
+		"clientport":               "source.port",
+		"elb":                      "aws.elb.name",
+		"error_code":               "aws.s3access.error_code",
+		"httpversion":              "http.version",
+		"key":                      "aws.s3access.key",
+		"object_size":              "aws.s3access.object_size",
+		"operation":                "aws.s3access.operation",
+		"owner":                    "aws.s3access.bucket_owner",
+		"params":                   "url.query",
+		"path":                     "url.path",
+		"proto":                    "url.scheme",
+		"rawrequest":               "aws.request_line",
+		"received_bytes":           "http.request.body.bytes",
+		"referrer":                 "http.request.referrer",
+		"request":                  "url.original",
+		"request_id":               "aws.s3access.request_id",
+		"request_processing_time":  "aws.elb.request_processing_time.sec",
+		"request_time_ms":          "aws.s3access.total_time",
+		"requester":                "aws.s3access.requester",
+		"response":                 "http.response.status_code",
+		"response_processing_time": "aws.elb.response_processing_time.sec",
+		"turnaround_time_ms":       "aws.s3access.turn_around_time",
+		"urihost":                  "aws.elb.request.host",
+		"verb":                     "http.request.method",
+		"version_id":               "aws.s3access.version_id",
+	},
+
+	"haproxy": {
+		"accept_date":               "haproxy.request_date",
+		"actconn":                   "haproxy.connections.active",
+		"backend_name":              "haproxy.backend_name",
+		"backend_queue":             "haproxy.backend_queue",
+		"beconn":                    "haproxy.connections.backend",
+		"bytes_read":                "source.bytes",
+		"captured_request_cookie":   "haproxy.http.request.captured_cookie",
+		"captured_request_headers":  "haproxy.http.request.captured_headers",
+		"captured_response_cookie":  "haproxy.http.response.captured_cookie",
+		"captured_response_headers": "haproxy.http.response.captured_headers",
+		"client_ip":                 "source.address",
+		"client_port":               "source.port",
+		"feconn":                    "haproxy.connections.frontend",
+		"frontend_name":             "haproxy.frontend_name",
+		"haproxy_hour":              "haproxy.date.hour",
+		"haproxy_milliseconds":      "haproxy.date.milliseconds",
+		"haproxy_minute":            "haproxy.date.minute",
+		"haproxy_month":             "haproxy.date.month",
+		"haproxy_monthday":          "haproxy.date.day",
+		"haproxy_second":            "haproxy.date.second",
+		"haproxy_time":              "haproxy.date.time",
+		"haproxy_year":              "haproxy.date.year",
+		"http_host":                 "haproxy.http.request.host",
+		"http_proto":                "url.scheme",
+		"http_request":              "url.original",
+		"http_status_code":          "http.response.status_code",
+		"http_user":                 "url.username",
+		"http_verb":                 "http.request.method",
+		"http_version":              "http.version",
+		"retries":                   "haproxy.connections.retries",
+		"server_name":               "haproxy.server_name",
+		"srv_queue":                 "haproxy.server_queue",
+		"srvconn":                   "haproxy.connections.server",
+		"syslog_server":             "host.hostname",
+		"syslog_timestamp":          "timestamp",
+		"termination_state":         "haproxy.termination_state",
+		"time_backend_connect":      "haproxy.connection_wait_time_ms",
+		"time_backend_response":     "haproxy.http.request.time_wait_without_data_ms",
+		"time_duration":             "haproxy.total_time_ms",
+		"time_queue":                "haproxy.total_waiting_time_ms",
+		"time_request":              "haproxy.http.request.time_wait_ms",
+		"timestamp8601":             "timestamp",
+	},
+
+	"linuxsyslog": {
+		"action":            "system.cron.action",
+		"logsource":         "host.hostname",
+		"pam_by":            "system.auth.pam.by",
+		"pam_caller":        "system.auth.pam.caller",
+		"pam_module":        "system.auth.pam.module",
+		"pam_session_state": "system.auth.pam.session_state",
+		"syslog5424_app":    "process.name",
+		"syslog5424_host":   "host.hostname",
+		"syslog5424_msg":    "message",
+		"syslog5424_msgid":  "event.code",
+		"syslog5424_pri":    "log.syslog.priority",
+		"syslog5424_proc":   "process.pid",
+		"syslog5424_sd":     "log.syslog.structured_data",
+		"syslog5424_ts":     "timestamp",
+		"syslog5424_ver":    "log.syslog.version",
+		"timestamp8601":     "timestamp",
+		"user":              "user.name",
+		"username":          "user.name",
+	},
+}
+
+var (
+	// ECSGrok is Grok with ECS field names
+	ECSGrok = renameFields(Grok, ECSFields["grok"])
+
+	// ECSAWS is AWS with ECS field names
+	ECSAWS = renameFields(AWS, ECSFields["aws"])
+
+	// ECSHaproxy is Haproxy with ECS field names
+	ECSHaproxy = renameFields(Haproxy, ECSFields["haproxy"])
+
+	// ECSLinuxSyslog is LinuxSyslog with ECS field names
+	ECSLinuxSyslog = renameFields(LinuxSyslog, ECSFields["linuxsyslog"])
+)
+
+// ECSPacks holds the ECS variants of the packs by the name of the pack, see
+// Packs. Use them with grok.Config.ECSCompatibility so that the default
+// patterns they refer to use ECS field names, too.
+var ECSPacks = map[string]map[string]string{
+	"aws":         ECSAWS,
+	"grok":        ECSGrok,
+	"haproxy":     ECSHaproxy,
+	"linuxsyslog": ECSLinuxSyslog,
+}
+
+var (
+	// captureName matches the name of %{PATTERN:name} and %{PATTERN:name:type}
+	captureName = regexp.MustCompile(`(%{\w+:)([\w.\[\]@-]+)((?::\w+)?})`)
+	// groupName matches the name of (?<name>
+	groupName = regexp.MustCompile(`(\(\?<)([\w.\[\]@-]+)(>)`)
+)
+
+// renameFields returns a copy of patterns with the named captures renamed as
+// listed in fields like grok.RenameFields, which cannot be used here as the
+// tests of the grok package import this package
+func renameFields(patterns map[string]string, fields map[string]string) map[string]string {
+	rename := func(re *regexp.Regexp) func(string) string {
+		return func(reference string) string {
+			parts := re.FindStringSubmatch(reference)
+			if field, mapped := fields[parts[2]]; mapped {
+				return parts[1] + field + parts[3]
+			}
+			return reference
+		}
+	}
+
+	renamed := make(map[string]string, len(patterns))
+	for name, definition := range patterns {
+		definition = captureName.ReplaceAllStringFunc(definition, rename(captureName))
+		renamed[name] = groupName.ReplaceAllStringFunc(definition, rename(groupName))
+	}
+	return renamed
+}
@@ -0,0 +1,99 @@
+package patterns_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/rtkjweeks/grok-go-pcre"
+	"github.com/rtkjweeks/grok-go-pcre/patterns"
+	"github.com/trivago/tgo/ttesting"
+)
+
+func TestECSCorpus(t *testing.T) {
+	for name, pack := range patterns.ECSPacks {
+		expect := ttesting.NewExpect(t)
+
+		data, err := patterns.ECSCorpus(name)
+		expect.NoError(err)
+		corpus, err := grok.ReadCorpus(bytes.NewReader(data))
+		expect.NoError(err)
+
+		config := packConfig(grok.ECSDefaultPatterns, pack)
+		config.ECSCompatibility = true
+		g, err := grok.New(config)
+		expect.NoError(err)
+
+		for _, failure := range grok.Verify(g, corpus) {
+			if _, isUnsupported := failure.Err.(*grok.UnsupportedSyntaxError); isUnsupported {
+				t.Logf("%s: skipped %s", name, failure)
+				continue
+			}
+			t.Errorf("%s: %s", name, failure)
+		}
+
+		// The fields of each sample must not conflict when nested, e.g.
+		// "url" and "url.port"
+		for _, sample := range corpus {
+			if len(sample.Fields) == 0 {
+				continue
+			}
+			values := make(map[string]interface{}, len(sample.Fields))
+			for key, value := range sample.Fields {
+				values[key] = value
+			}
+			if _, err := grok.Nest(values); err != nil {
+				t.Errorf("%s: %s: %s", name, sample.Pattern, err)
+			}
+		}
+
+		graph := g.Dependencies()
+		for pattern := range pack {
+			if len(graph.Dependents(pattern)) == 0 && !isCovered(corpus, pattern) {
+				t.Errorf("%s: no sample for %s", name, pattern)
+			}
+		}
+	}
+
+	_, err := patterns.ECSCorpus("redis")
+	ttesting.NewExpect(t).NotNil(err)
+}
+
+func TestECSFieldNames(t *testing.T) {
+	for name, pack := range patterns.ECSPacks {
+		expect := ttesting.NewExpect(t)
+
+		config := packConfig(grok.ECSDefaultPatterns, pack)
+		config.ECSCompatibility = true
+		g, err := grok.New(config)
+		expect.NoError(err)
+
+		// Every field is either an ECS field set like "source.ip" or one of
+		// the top level fields of ECS
+		for pattern, bundled := range g.Bundle().Patterns {
+			for _, field := range bundled.Aliases {
+				if !strings.Contains(field, ".") && field != "timestamp" && field != "message" {
+					t.Errorf("%s: %s captures %s, which is not mapped to ECS", name, pattern, field)
+				}
+			}
+		}
+
+		for legacy := range patterns.ECSFields[name] {
+			if _, isDefault := grok.ECSDefaultFields[legacy]; name != "grok" && isDefault {
+				continue
+			}
+			expect.True(capturesField(patterns.Packs[name], legacy))
+		}
+	}
+}
+
+// capturesField returns true if one of the patterns has a capture of the
+// given name
+func capturesField(pack map[string]string, field string) bool {
+	for _, definition := range pack {
+		if strings.Contains(definition, ":"+field+"}") || strings.Contains(definition, ":"+field+":") {
+			return true
+		}
+	}
+	return false
+}
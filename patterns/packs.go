@@ -0,0 +1,23 @@
+package patterns
+
+// Packs holds all pattern collections of this package by a short, lowercase
+// name. It can be used to select packs by name, e.g. from a command line.
+var Packs = map[string]map[string]string{
+	"aws":         AWS,
+	"bacula":      Bacula,
+	"bro":         Bro,
+	"exim":        Exim,
+	"firewalls":   Firewalls,
+	"grok":        Grok,
+	"haproxy":     Haproxy,
+	"java":        Java,
+	"junos":       Junos,
+	"linuxsyslog": LinuxSyslog,
+	"mcollective": MCollective,
+	"mongodb":     MongoDB,
+	"nagios":      Nagios,
+	"postgresql":  PostgreSQL,
+	"rails":       Rails,
+	"redis":       Redis,
+	"ruby":        Ruby,
+}
@@ -2,7 +2,7 @@ package patterns
 
 // Rails is a collection of patterns used for Ruby on Rails.
 var Rails = map[string]string{
-	"RUUID": `\h{32}`,
+	"RUUID": `[0-9a-fA-F]{32}`,
 	// rails controller with action
 	"RCONTROLLER": `(?<controller>[^#]+)#(?<action>\w+)`,
 
@@ -0,0 +1,69 @@
+package grok
+
+// PatternSet matches texts against a list of expressions and returns the
+// captures of the first one that matches, e.g. to parse the different
+// message formats of a single source.
+type PatternSet struct {
+	names    []string
+	compiled []*CompiledGrok
+}
+
+// CompilePatternSet compiles the given expressions into a PatternSet. The
+// expressions are tried in the given order and named after themselves.
+func (grok Grok) CompilePatternSet(expressions ...string) (*PatternSet, error) {
+	set := &PatternSet{}
+	for _, expression := range expressions {
+		compiled, err := grok.Compile(expression)
+		if err != nil {
+			return nil, err
+		}
+		set.Add(expression, compiled)
+	}
+	return set, nil
+}
+
+// Add appends a compiled expression to the set. The name is returned by the
+// match functions if this expression matches. Add must not be called while
+// the set is in use.
+func (set *PatternSet) Add(name string, compiled *CompiledGrok) {
+	set.names = append(set.names, name)
+	set.compiled = append(set.compiled, compiled)
+}
+
+// Names returns the names of the expressions in the order they are tried.
+func (set *PatternSet) Names() []string {
+	return append([]string(nil), set.names...)
+}
+
+// Lookup returns the expression of the given name, nil if it is not known.
+func (set *PatternSet) Lookup(name string) *CompiledGrok {
+	for i, candidate := range set.names {
+		if candidate == name {
+			return set.compiled[i]
+		}
+	}
+	return nil
+}
+
+// MatchAgainst works like CompiledGrok.MatchAgainst using the first matching
+// expression. Its name is returned, too.
+func (set *PatternSet) MatchAgainst(text string) (bool, map[string]string, string) {
+	for i, compiled := range set.compiled {
+		if matched, values := compiled.MatchAgainst(text); matched {
+			return true, values, set.names[i]
+		}
+	}
+	return false, map[string]string{}, ""
+}
+
+// MatchAgainstTyped works like CompiledGrok.MatchAgainstTyped using the
+// first matching expression. Its name is returned, too.
+func (set *PatternSet) MatchAgainstTyped(text string) (bool, map[string]interface{}, string, error) {
+	for i, compiled := range set.compiled {
+		matched, values, err := compiled.MatchAgainstTyped(text)
+		if matched || err != nil {
+			return matched, values, set.names[i], err
+		}
+	}
+	return false, map[string]interface{}{}, "", nil
+}
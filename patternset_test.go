@@ -0,0 +1,43 @@
+package grok
+
+import (
+	"github.com/trivago/tgo/ttesting"
+	"testing"
+)
+
+func TestPatternSet(t *testing.T) {
+	expect := ttesting.NewExpect(t)
+	g, _ := New(Config{NamedCapturesOnly: true})
+
+	set, err := g.CompilePatternSet("%{IP:client} %{NUMBER:bytes:int}", "%{WORD:verb} %{NUMBER:bytes:int}")
+	expect.NoError(err)
+	expect.Equal([]string{"%{IP:client} %{NUMBER:bytes:int}", "%{WORD:verb} %{NUMBER:bytes:int}"}, set.Names())
+
+	matched, values, name := set.MatchAgainst("GET 42")
+	expect.True(matched)
+	expect.Equal("%{WORD:verb} %{NUMBER:bytes:int}", name)
+	delete(values, "")
+	expect.Equal(map[string]string{"verb": "GET", "bytes": "42"}, values)
+
+	matched, typed, name, err := set.MatchAgainstTyped("10.0.0.1 42")
+	expect.True(matched)
+	expect.NoError(err)
+	expect.Equal("%{IP:client} %{NUMBER:bytes:int}", name)
+	expect.Equal(map[string]interface{}{"client": "10.0.0.1", "bytes": 42}, typed)
+
+	matched, _, name = set.MatchAgainst("-")
+	expect.False(matched)
+	expect.Equal("", name)
+
+	compiled, _ := g.Compile("%{GREEDYDATA:message}")
+	set.Add("fallback", compiled)
+	matched, values, name = set.MatchAgainst("-")
+	expect.True(matched)
+	expect.Equal("fallback", name)
+	expect.Equal("-", values["message"])
+	expect.Equal(compiled, set.Lookup("fallback"))
+	expect.Nil(set.Lookup("unknown"))
+
+	_, err = g.CompilePatternSet("%{IP:client}", "%{UNKNOWN}")
+	expect.NotNil(err)
+}
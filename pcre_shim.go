@@ -1,3 +1,6 @@
+//go:build cgo && !nopcre
+// +build cgo,!nopcre
+
 package grok
 
 import (
@@ -12,11 +15,6 @@ func maxInt(x, y int) int {
 	}
 }
 
-type GrokReplacementMatch struct {
-	NameAndAlias string
-	FullTag string
-}
-
 // This is a slight tweak of FindAll in the go-pcre package:
 // https://github.com/rubrikinc/go-pcre/blob/master/pcre.go#L633
 //
@@ -44,10 +42,17 @@ func FindAllSubstring(re pcre.Regexp, subject string, flags int) ([]GrokReplacem
 		leftIdx := loc[0] + offset
 		rightIdx := loc[1] + offset
 
+		// strs[0] is the whole thing, strs[1] is the capture. Expressions
+		// without a capture group leave NameAndAlias empty.
+		capture := ""
+		if len(strs) > 1 {
+			capture = strs[1]
+		}
+
 		matches = append(
 			matches,
 			GrokReplacementMatch{
-				strs[1],  // strs[0] is the whole thing, strs[1] is the capture
+				capture,
 				subject[leftIdx:rightIdx], // the whole tag
 			},
 		)
@@ -0,0 +1,181 @@
+package grok
+
+import (
+	"errors"
+	"runtime"
+	"sync"
+)
+
+// ErrPipelineClosed is returned when submitting to a pipeline that has
+// already been closed.
+var ErrPipelineClosed = errors.New("grok: pipeline is closed")
+
+// PipelineConfig is used to pass a set of configuration values to the
+// CompiledGrok.NewPipeline function.
+type PipelineConfig struct {
+	// Workers is the number of goroutines matching records. Defaults to
+	// runtime.NumCPU() if not set.
+	Workers int
+	// QueueSize is the number of records that may be in flight (queued,
+	// being matched or waiting to be delivered) before Submit blocks.
+	// Defaults to 2*Workers if not set.
+	QueueSize int
+	// PreserveOrder delivers results in the order the records were submitted.
+	// If not set, results are delivered as soon as they are matched.
+	PreserveOrder bool
+	// Redactor, if set, matches the records through Redactor.MatchAgainst,
+	// so the values are redacted. It must belong to the same expression.
+	Redactor *Redactor
+}
+
+// PipelineResult holds the outcome of matching a single record.
+type PipelineResult struct {
+	Index   uint64
+	Text    string
+	Matched bool
+	Values  map[string]string
+	// Redacted is the text with all redacted captures replaced. It is only
+	// set if the pipeline has a Redactor and the record matched.
+	Redacted string
+}
+
+type pipelineRecord struct {
+	index uint64
+	text  string
+}
+
+// Pipeline fans records out to a set of workers, each owning its own matcher,
+// and fans the results back in on a single channel.
+// Submit blocks once QueueSize records are in flight, so a slow consumer of
+// Results slows down the producer instead of growing unbounded buffers.
+type Pipeline struct {
+	compiled  *CompiledGrok
+	input     chan pipelineRecord
+	matched   chan PipelineResult
+	results   chan PipelineResult
+	inFlight  chan struct{}
+	workers   sync.WaitGroup
+	nextIndex uint64
+	closed    bool
+	ordered   bool
+	redactor  *Redactor
+}
+
+// NewPipeline starts a pipeline matching records against this expression.
+// Results must be consumed from Results until it is closed, otherwise the
+// pipeline stalls once QueueSize records are in flight.
+func (compiled *CompiledGrok) NewPipeline(config PipelineConfig) *Pipeline {
+	workers := config.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	queueSize := config.QueueSize
+	if queueSize <= 0 {
+		queueSize = 2 * workers
+	}
+
+	p := &Pipeline{
+		compiled: compiled,
+		input:    make(chan pipelineRecord, queueSize),
+		matched:  make(chan PipelineResult, workers),
+		results:  make(chan PipelineResult, queueSize),
+		inFlight: make(chan struct{}, queueSize),
+		ordered:  config.PreserveOrder,
+		redactor: config.Redactor,
+	}
+
+	p.workers.Add(workers)
+	for i := 0; i < workers; i++ {
+		go p.work()
+	}
+	go func() {
+		p.workers.Wait()
+		close(p.matched)
+	}()
+	go p.collect()
+
+	return p
+}
+
+// Submit queues a record for matching. It blocks while the pipeline is at
+// capacity. Submit must not be called concurrently with itself or Close.
+func (p *Pipeline) Submit(text string) error {
+	if p.closed {
+		return ErrPipelineClosed
+	}
+	p.inFlight <- struct{}{}
+	p.input <- pipelineRecord{index: p.nextIndex, text: text}
+	p.nextIndex++
+	return nil
+}
+
+// Results returns the channel results are delivered on. The channel is
+// closed after Close has been called and all pending records were delivered.
+func (p *Pipeline) Results() <-chan PipelineResult {
+	return p.results
+}
+
+// Close stops accepting new records. Records already submitted are still
+// matched and delivered before Results is closed.
+func (p *Pipeline) Close() {
+	if p.closed {
+		return
+	}
+	p.closed = true
+	close(p.input)
+}
+
+// work matches records using a matcher owned by this worker
+func (p *Pipeline) work() {
+	defer p.workers.Done()
+	matcher := p.compiled.regexp.NewMatcher()
+
+	for record := range p.input {
+		result := PipelineResult{Index: record.index, Text: record.text}
+		if p.redactor != nil {
+			result.Matched, result.Values, result.Redacted = p.redactor.matchAgainst(matcher, record.text)
+			if !result.Matched {
+				result.Redacted = ""
+			}
+		} else {
+			result.Matched, result.Values = p.compiled.matchAgainst(matcher, record.text)
+		}
+		p.matched <- result
+	}
+}
+
+// collect forwards matched records to the results channel, restoring the
+// submission order if requested.
+func (p *Pipeline) collect() {
+	defer close(p.results)
+
+	if !p.ordered {
+		for result := range p.matched {
+			p.deliver(result)
+		}
+		return
+	}
+
+	// Out of order results are parked until all preceding records have been
+	// delivered. This buffer is bounded by QueueSize through inFlight.
+	pending := make(map[uint64]PipelineResult)
+	next := uint64(0)
+	for result := range p.matched {
+		pending[result.Index] = result
+		for {
+			parked, ready := pending[next]
+			if !ready {
+				break
+			}
+			delete(pending, next)
+			p.deliver(parked)
+			next++
+		}
+	}
+}
+
+// deliver hands a result to the consumer and frees its in-flight slot
+func (p *Pipeline) deliver(result PipelineResult) {
+	p.results <- result
+	<-p.inFlight
+}
@@ -0,0 +1,94 @@
+package grok
+
+import (
+	"fmt"
+	"github.com/trivago/tgo/ttesting"
+	"testing"
+)
+
+func TestPipelineOrdered(t *testing.T) {
+	expect := ttesting.NewExpect(t)
+
+	g, err := New(Config{NamedCapturesOnly: true})
+	expect.NoError(err)
+
+	c, err := g.Compile("%{WORD:word} %{NUMBER:num}")
+	expect.NoError(err)
+
+	p := c.NewPipeline(PipelineConfig{Workers: 4, QueueSize: 3, PreserveOrder: true})
+	go func() {
+		for i := 0; i < 100; i++ {
+			if i%10 == 0 {
+				p.Submit("no match")
+			} else {
+				p.Submit(fmt.Sprintf("line %d", i))
+			}
+		}
+		p.Close()
+	}()
+
+	n := uint64(0)
+	for result := range p.Results() {
+		expect.Equal(n, result.Index)
+		if n%10 == 0 {
+			expect.False(result.Matched)
+		} else {
+			expect.True(result.Matched)
+			expect.MapEqual(result.Values, "num", fmt.Sprintf("%d", n))
+		}
+		n++
+	}
+	expect.Equal(uint64(100), n)
+	expect.Equal(ErrPipelineClosed, p.Submit("line 1"))
+}
+
+func TestPipelineUnordered(t *testing.T) {
+	expect := ttesting.NewExpect(t)
+
+	g, err := New(Config{NamedCapturesOnly: true})
+	expect.NoError(err)
+
+	c, err := g.Compile("%{NUMBER:num}")
+	expect.NoError(err)
+
+	p := c.NewPipeline(PipelineConfig{Workers: 4})
+	go func() {
+		for i := 0; i < 100; i++ {
+			p.Submit(fmt.Sprintf("%d", i))
+		}
+		p.Close()
+	}()
+
+	seen := make(map[uint64]bool)
+	for result := range p.Results() {
+		expect.True(result.Matched)
+		expect.MapEqual(result.Values, "num", fmt.Sprintf("%d", result.Index))
+		seen[result.Index] = true
+	}
+	expect.Equal(100, len(seen))
+}
+
+func benchmarkPipelineCaptures(b *testing.B, preserveOrder bool) {
+	g, _ := New(Config{NamedCapturesOnly: true})
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	c, _ := g.Compile(`%{IPORHOST:clientip} %{USER:ident} %{USER:auth} \[%{HTTPDATE:timestamp}\] "(?:%{WORD:verb} %{NOTSPACE:request}(?: HTTP/%{NUMBER:httpversion})?|%{DATA:rawrequest})" %{NUMBER:response} (?:%{NUMBER:bytes}|-)`)
+	p := c.NewPipeline(PipelineConfig{PreserveOrder: preserveOrder})
+	go func() {
+		for n := 0; n < b.N; n++ {
+			p.Submit(`127.0.0.1 - - [23/Apr/2014:22:58:32 +0200] "GET /index.php HTTP/1.1" 404 207`)
+		}
+		p.Close()
+	}()
+	for range p.Results() {
+	}
+}
+
+func BenchmarkPipelineCaptures(b *testing.B) {
+	benchmarkPipelineCaptures(b, false)
+}
+
+func BenchmarkPipelineOrderedCaptures(b *testing.B) {
+	benchmarkPipelineCaptures(b, true)
+}
@@ -0,0 +1,352 @@
+package grok
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// profileRounds is the number of times Profile matches each sample. The
+// fastest round is used as the match time to reduce noise.
+const profileRounds = 5
+
+// ProfileMaxSteps is the highest number of steps Profile counts for a single
+// match. Matches taking more steps are counted as limit hits. It equals the
+// default match limit of libpcre.
+var ProfileMaxSteps = 10000000
+
+// ProfileResult holds the outcome of Grok.Profile.
+type ProfileResult struct {
+	Expression string
+	Samples    int
+	Matched    int
+	// Average, P99 and Max are the match times of the samples
+	Average time.Duration
+	P99     time.Duration
+	Max     time.Duration
+	// Slowest is the sample with the highest match time
+	Slowest string
+	// Steps is the average and MaxSteps the highest number of steps the
+	// engine took for a sample. Both are -1 if the engine cannot count
+	// steps, see StepEngine.
+	Steps    float64
+	MaxSteps int
+	// LimitHits counts the samples taking more than ProfileMaxSteps steps
+	LimitHits int
+	// Costs attributes the cost of matching to the components of the
+	// expression, most expensive first.
+	Costs []ProfileCost
+}
+
+// ProfileCost is the cost a component adds to matching an expression. The
+// expression is extended component by component like in Debug and each
+// component is charged with the difference to the expression without it.
+// This includes backtracking into earlier components caused by it.
+// The most expensive reference or group is expanded, so the costs of the
+// components listed with a Path add up to the cost of the last element of
+// the path.
+type ProfileCost struct {
+	// Path lists the references and groups expanded to reach Component,
+	// outermost first
+	Path      []string
+	Component string
+	// Time is the average time and Steps the average number of steps added
+	// per sample. Steps is -1 if the engine cannot count steps.
+	Time  time.Duration
+	Steps float64
+	// Share is the ratio of the cost of the whole expression, based on
+	// steps if available and on time otherwise. Shares based on time are
+	// subject to noise and capped at 1.
+	Share float64
+}
+
+// profileRun holds the match time and steps of each sample
+type profileRun struct {
+	times   []time.Duration
+	matched []bool
+	// steps is nil if the engine cannot count steps
+	steps   []int
+	limited []bool
+}
+
+// Profile matches the samples against a grok expression and reports how
+// long matching takes and which part of the expression causes the cost.
+// If the engine is a StepEngine, like PCRE, the steps of the backtracking
+// matcher are counted as well, which is more reliable than timing.
+// This function is expensive as it compiles and matches many partial
+// expressions and is meant to be used for troubleshooting only.
+func (grok Grok) Profile(expression string, samples []string) (*ProfileResult, error) {
+	run, err := grok.profileRun(expression, samples)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ProfileResult{
+		Expression: expression,
+		Samples:    len(samples),
+		Steps:      -1,
+		MaxSteps:   -1,
+	}
+	if len(samples) == 0 {
+		return result, nil
+	}
+
+	for i, duration := range run.times {
+		if run.matched[i] {
+			result.Matched++
+		}
+		if duration > result.Max {
+			result.Max, result.Slowest = duration, samples[i]
+		}
+	}
+	result.Average = run.totalTime() / time.Duration(len(samples))
+	sorted := append([]time.Duration{}, run.times...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	result.P99 = sorted[(len(sorted)*99+99)/100-1]
+
+	if run.steps != nil {
+		result.MaxSteps = 0
+		for i, steps := range run.steps {
+			if steps > result.MaxSteps {
+				result.MaxSteps = steps
+			}
+			if run.limited[i] {
+				result.LimitHits++
+			}
+		}
+		result.Steps = float64(run.totalSteps()) / float64(len(samples))
+	}
+
+	result.Costs = grok.profileComponents("", expression, []string{}, samples, &profileRun{}, run.cost(), 0)
+	sort.SliceStable(result.Costs, func(i, j int) bool {
+		return result.Costs[i].Share > result.Costs[j].Share
+	})
+	return result, nil
+}
+
+// profileComponents extends prefix by the components of each top level
+// alternative of expression and charges each component with the cost it
+// adds. base is the run of prefix and total the cost of the profiled
+// expression. The most expensive component of each alternative is expanded
+// if possible.
+func (grok Grok) profileComponents(prefix, expression string, path []string, samples []string, base *profileRun, total float64, depth int) []ProfileCost {
+	costs := []ProfileCost{}
+	for _, components := range splitAlternatives(splitComponents(expression)) {
+		previous, current := base, prefix
+		worst, worstCost, worstBase, worstPrefix := "", 0.0, base, prefix
+
+		for _, component := range components {
+			run, err := grok.profileRun(current+component, samples)
+			if err != nil {
+				break // the remaining components depend on this one
+			}
+
+			// Adding a component may make matching cheaper, e.g. by failing
+			// early, which is not a cost.
+			cost := ProfileCost{Path: path, Component: component, Steps: -1}
+			if added := run.totalTime() - previous.totalTime(); added > 0 {
+				cost.Time = added / time.Duration(len(samples))
+			}
+			if run.steps != nil {
+				cost.Steps = 0
+				if added := run.totalSteps() - previous.totalSteps(); added > 0 {
+					cost.Steps = float64(added) / float64(len(samples))
+				}
+			}
+			if delta := run.cost() - previous.cost(); total > 0 && delta > 0 {
+				cost.Share = math.Min(delta/total, 1)
+			}
+			costs = append(costs, cost)
+
+			if cost.Share > worstCost {
+				worst, worstCost, worstBase, worstPrefix = component, cost.Share, previous, current
+			}
+			previous, current = run, current+component
+		}
+
+		if inner := grok.innerExpression(worst); len(inner) > 0 && depth < 8 {
+			innerPath := append(append([]string{}, path...), worst)
+			costs = append(costs, grok.profileComponents(worstPrefix, inner, innerPath, samples, worstBase, total, depth+1)...)
+		}
+	}
+	return costs
+}
+
+// profileRun matches each sample against an expression. The empty
+// expression is not matched and has no cost.
+func (grok Grok) profileRun(expression string, samples []string) (*profileRun, error) {
+	run := &profileRun{
+		times:   make([]time.Duration, len(samples)),
+		matched: make([]bool, len(samples)),
+	}
+	if len(expression) == 0 {
+		return run, nil
+	}
+
+	compiled, err := grok.Compile(expression)
+	if err != nil {
+		return nil, err
+	}
+	matcher := compiled.regexp.NewMatcher()
+	for i, sample := range samples {
+		for round := 0; round < profileRounds; round++ {
+			start := time.Now()
+			run.matched[i] = matcher.MatchString(sample)
+			if duration := time.Since(start); round == 0 || duration < run.times[i] {
+				run.times[i] = duration
+			}
+		}
+	}
+
+	counter := grok.newStepCounter(compiled.pattern.expression)
+	if counter == nil {
+		return run, nil
+	}
+	run.steps = make([]int, len(samples))
+	run.limited = make([]bool, len(samples))
+	for i, sample := range samples {
+		run.steps[i], run.limited[i] = counter.count(sample)
+	}
+	return run, nil
+}
+
+func (run *profileRun) totalTime() time.Duration {
+	total := time.Duration(0)
+	for _, duration := range run.times {
+		total += duration
+	}
+	return total
+}
+
+func (run *profileRun) totalSteps() int {
+	total := 0
+	for _, steps := range run.steps {
+		total += steps
+	}
+	return total
+}
+
+// cost returns the steps of all samples if they were counted and the time
+// taken otherwise
+func (run *profileRun) cost() float64 {
+	if run.steps != nil {
+		return float64(run.totalSteps())
+	}
+	return float64(run.totalTime())
+}
+
+// stepCounter counts the steps of matches by probing an expression with
+// increasing limits. The expressions compiled for powers of two are cached
+// as every sample needs them.
+type stepCounter struct {
+	engine     StepEngine
+	expression string
+	cached     map[int]Regexp
+}
+
+// newStepCounter returns a counter for an expanded expression or nil if the
+// engine cannot count steps
+func (grok Grok) newStepCounter(expression string) *stepCounter {
+	engine, isStepEngine := grok.engine.(StepEngine)
+	if !isStepEngine {
+		return nil
+	}
+	counter := &stepCounter{
+		engine:     engine,
+		expression: expression,
+		cached:     make(map[int]Regexp),
+	}
+	if _, err := counter.exceeds(1, ""); err != nil {
+		return nil
+	}
+	return counter
+}
+
+// count returns the number of steps needed to match subject. Matches
+// exceeding ProfileMaxSteps return ProfileMaxSteps and true.
+// Counts above 128 are accurate to about one percent.
+func (counter *stepCounter) count(subject string) (int, bool) {
+	low, high := 0, 1
+	for {
+		exceeded, err := counter.exceeds(high, subject)
+		if err != nil {
+			return 0, false
+		}
+		if !exceeded {
+			break
+		}
+		if high >= ProfileMaxSteps {
+			return ProfileMaxSteps, true
+		}
+		low, high = high, minInt(high*2, ProfileMaxSteps)
+	}
+
+	for high-low > 1 && high-low > high/128 {
+		middle := low + (high-low)/2
+		exceeded, err := counter.exceeds(middle, subject)
+		if err != nil {
+			break
+		}
+		if exceeded {
+			low = middle
+		} else {
+			high = middle
+		}
+	}
+	return high, false
+}
+
+// exceeds returns true if matching subject takes more than limit steps
+func (counter *stepCounter) exceeds(limit int, subject string) (bool, error) {
+	compiled, isCached := counter.cached[limit]
+	if !isCached {
+		var err error
+		if compiled, err = counter.engine.CompileLimited(counter.expression, limit); err != nil {
+			return false, err
+		}
+		if limit&(limit-1) == 0 {
+			counter.cached[limit] = compiled
+		}
+	}
+
+	matcher := compiled.NewMatcher()
+	matcher.MatchString(subject)
+	limited, isReporter := matcher.(LimitReporter)
+	return isReporter && limited.LimitExceeded(), nil
+}
+
+// ProfileCorpus profiles each pattern of a corpus with the inputs of its
+// samples. The results are sorted by the average number of steps if the
+// engine counts steps and by the average match time otherwise, the most
+// expensive pattern first.
+func ProfileCorpus(grok *Grok, corpus Corpus) ([]*ProfileResult, error) {
+	inputs := make(map[string][]string)
+	for _, sample := range corpus {
+		inputs[sample.Pattern] = append(inputs[sample.Pattern], sample.Input)
+	}
+
+	results := make([]*ProfileResult, 0, len(inputs))
+	countedSteps := true
+	for _, name := range corpus.Patterns() {
+		expression := name
+		if !strings.Contains(expression, "%{") {
+			expression = "%{" + expression + "}"
+		}
+		result, err := grok.Profile(expression, inputs[name])
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", name, err)
+		}
+		countedSteps = countedSteps && result.Steps >= 0
+		results = append(results, result)
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		if countedSteps {
+			return results[i].Steps > results[j].Steps
+		}
+		return results[i].Average > results[j].Average
+	})
+	return results, nil
+}
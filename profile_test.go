@@ -0,0 +1,148 @@
+package grok
+
+import (
+	"github.com/trivago/tgo/ttesting"
+	"strings"
+	"testing"
+)
+
+// stepEngine takes a step per character of the subject for each wildcard
+// of the expression, plus one. Subjects starting with "limit" take more
+// steps than ProfileMaxSteps.
+type stepEngine struct{}
+
+type stepRegexp struct {
+	Regexp
+	wildcards int
+	limit     int
+}
+
+type stepMatcher struct {
+	Matcher
+	regexp  stepRegexp
+	limited bool
+}
+
+func (engine stepEngine) Name() string {
+	return "steps"
+}
+
+func (engine stepEngine) Compile(expression string) (Regexp, error) {
+	return engine.CompileLimited(expression, 0)
+}
+
+func (engine stepEngine) CompileLimited(expression string, limit int) (Regexp, error) {
+	compiled, err := RE2.Compile(expression)
+	if err != nil {
+		return nil, err
+	}
+	return stepRegexp{compiled, strings.Count(expression, ".*"), limit}, nil
+}
+
+func (re stepRegexp) NewMatcher() Matcher {
+	return &stepMatcher{Matcher: re.Regexp.NewMatcher(), regexp: re}
+}
+
+func (m *stepMatcher) MatchString(subject string) bool {
+	steps := 1 + m.regexp.wildcards*len(subject)
+	if strings.HasPrefix(subject, "limit") {
+		steps = ProfileMaxSteps + 1
+	}
+	m.limited = m.regexp.limit > 0 && steps > m.regexp.limit
+	return !m.limited && m.Matcher.MatchString(subject)
+}
+
+func (m *stepMatcher) LimitExceeded() bool {
+	return m.limited
+}
+
+func TestProfile(t *testing.T) {
+	expect := ttesting.NewExpect(t)
+	g, _ := New(Config{NamedCapturesOnly: true, Engine: stepEngine{}})
+
+	result, err := g.Profile("%{WORD:verb} %{DATA:path} x", []string{"GET abc x", "GET abcdef x", "-"})
+	expect.NoError(err)
+	expect.Equal(3, result.Samples)
+	expect.Equal(2, result.Matched)
+	expect.Equal(13, result.MaxSteps)
+	expect.Equal(25.0/3.0, result.Steps)
+	expect.Equal(0, result.LimitHits)
+	expect.True(result.Max >= result.P99 && result.P99 >= result.Average)
+
+	expect.Equal(5, len(result.Costs))
+	expect.Equal("%{DATA:path}", result.Costs[0].Component)
+	expect.Equal(0, len(result.Costs[0].Path))
+	expect.Equal(22.0/3.0, result.Costs[0].Steps)
+	expect.Equal(22.0/25.0, result.Costs[0].Share)
+	expect.Equal("%{WORD:verb}", result.Costs[1].Component)
+	expect.Equal(3.0/25.0, result.Costs[1].Share)
+
+	result, err = g.Profile("%{WORD:verb}", []string{"limit"})
+	expect.NoError(err)
+	expect.Equal(1, result.LimitHits)
+	expect.Equal(ProfileMaxSteps, result.MaxSteps)
+
+	_, err = g.Profile("%{UNKNOWN}", []string{"x"})
+	expect.NotNil(err)
+}
+
+func TestProfileReferences(t *testing.T) {
+	expect := ttesting.NewExpect(t)
+	g, _ := New(Config{
+		NamedCapturesOnly: true,
+		Engine:            stepEngine{},
+		Patterns:          map[string]string{"REQUEST": `%{WORD:verb} (?:%{NOTSPACE:path}|%{GREEDYDATA:raw})`},
+	})
+
+	result, err := g.Profile("%{REQUEST} %{INT:status}", []string{"GET / 200"})
+	expect.NoError(err)
+	expect.Equal("%{REQUEST}", result.Costs[0].Component)
+	expect.Equal(1.0, result.Costs[0].Share)
+	expect.Equal([]string{"%{REQUEST}"}, result.Costs[1].Path)
+	expect.Equal("(?:%{NOTSPACE:path}|%{GREEDYDATA:raw})", result.Costs[1].Component)
+	expect.Equal([]string{"%{REQUEST}", "(?:%{NOTSPACE:path}|%{GREEDYDATA:raw})"}, result.Costs[2].Path)
+	expect.Equal("%{GREEDYDATA:raw}", result.Costs[2].Component)
+	expect.Equal(9.0, result.Costs[2].Steps)
+	expect.Equal(0.9, result.Costs[2].Share)
+}
+
+func TestProfileTime(t *testing.T) {
+	expect := ttesting.NewExpect(t)
+	g, _ := New(Config{NamedCapturesOnly: true, Engine: RE2})
+
+	result, err := g.Profile("%{WORD:verb} %{GREEDYDATA:rest}", []string{"GET /", "POST /index.html"})
+	expect.NoError(err)
+	expect.Equal(2, result.Matched)
+	expect.Equal(-1.0, result.Steps)
+	expect.Equal(-1, result.MaxSteps)
+	expect.True(result.Slowest == "GET /" || result.Slowest == "POST /index.html")
+	expect.Equal(3, len(result.Costs))
+	for _, cost := range result.Costs {
+		expect.Equal(-1.0, cost.Steps)
+	}
+
+	result, err = g.Profile("%{WORD:verb}", nil)
+	expect.NoError(err)
+	expect.Equal(0, result.Samples)
+	expect.Equal(0, len(result.Costs))
+}
+
+func TestProfileCorpus(t *testing.T) {
+	expect := ttesting.NewExpect(t)
+	g, _ := New(Config{NamedCapturesOnly: true, Engine: stepEngine{}})
+
+	results, err := ProfileCorpus(g, Corpus{
+		{Pattern: "INT", Input: "12"},
+		{Pattern: "%{WORD} %{GREEDYDATA}", Input: "a b"},
+		{Pattern: "INT", Input: "x", NoMatch: true},
+	})
+	expect.NoError(err)
+	expect.Equal(2, len(results))
+	expect.Equal("%{WORD} %{GREEDYDATA}", results[0].Expression)
+	expect.Equal("%{INT}", results[1].Expression)
+	expect.Equal(2, results[1].Samples)
+	expect.Equal(1, results[1].Matched)
+
+	_, err = ProfileCorpus(g, Corpus{{Pattern: "UNKNOWN", Input: "x"}})
+	expect.NotNil(err)
+}
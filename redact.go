@@ -0,0 +1,420 @@
+package grok
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// RedactAction defines how a Redactor changes a captured value.
+type RedactAction int
+
+const (
+	// RedactDrop removes the field from the result and its value from the
+	// line.
+	RedactDrop = RedactAction(iota)
+	// RedactMask replaces each character with an asterisk. The last
+	// RedactRule.Keep characters stay readable.
+	RedactMask
+	// RedactHash replaces the value with the hex encoded HMAC-SHA256 of the
+	// value, keyed with RedactRule.Key. Equal values produce equal hashes, so
+	// they can still be correlated.
+	RedactHash
+	// RedactTruncate keeps the first RedactRule.Keep characters.
+	RedactTruncate
+	// RedactIPPrefix zeroes the host part of an IP address, keeping the
+	// prefix given by RedactRule.IPv4Bits and RedactRule.IPv6Bits. Values
+	// that are not an IP address are masked.
+	RedactIPPrefix
+)
+
+// String returns the name of the action.
+func (action RedactAction) String() string {
+	switch action {
+	case RedactDrop:
+		return "drop"
+	case RedactMask:
+		return "mask"
+	case RedactHash:
+		return "hash"
+	case RedactTruncate:
+		return "truncate"
+	case RedactIPPrefix:
+		return "ipprefix"
+	default:
+		return fmt.Sprintf("RedactAction(%d)", int(action))
+	}
+}
+
+// ParseRedactAction returns the action of the given name, see String.
+func ParseRedactAction(name string) (RedactAction, error) {
+	for action := RedactDrop; action <= RedactIPPrefix; action++ {
+		if action.String() == name {
+			return action, nil
+		}
+	}
+	return RedactDrop, fmt.Errorf("unknown redact action %s, use drop, mask, hash, truncate or ipprefix", name)
+}
+
+// RedactRule selects captures by their name or by the pattern they were
+// captured with and defines how to redact them. Patterns referenced without
+// a name do not create a capture if NamedCapturesOnly is set, so they can
+// only be selected by the name of an enclosing capture.
+type RedactRule struct {
+	// Fields are the names of the captures to redact, e.g. "clientip"
+	Fields []string
+	// Patterns are the names of the patterns whose captures are redacted,
+	// e.g. "EMAILADDRESS". Only captures of the pattern itself are selected,
+	// not the ones of patterns it refers to.
+	Patterns []string
+	Action   RedactAction
+	// Key is the secret used by RedactHash
+	Key []byte
+	// Keep is the number of characters kept by RedactMask and
+	// RedactTruncate
+	Keep int
+	// IPv4Bits and IPv6Bits are the prefix lengths kept by RedactIPPrefix.
+	// They default to 24 and 48.
+	IPv4Bits int
+	IPv6Bits int
+}
+
+// Redactor applies redaction rules to the captures of a compiled grok
+// expression. It is safe for concurrent use.
+type Redactor struct {
+	compiled *CompiledGrok
+	// rules holds the rule for each group index, nil if not redacted
+	rules []*RedactRule
+	// fields holds the rule for each field name redacted in all groups
+	fields map[string]*RedactRule
+	// redacted holds the names of the fields that may contain a redacted
+	// value, including the ones enclosing a redacted group
+	redacted map[string]bool
+}
+
+// NewRedactor returns a Redactor for the captures of this expression. If
+// more than one rule selects a capture, the first one is applied. An error is
+// returned if a rule selects no capture, e.g. because of a typo or because
+// the pattern is referenced without a name while NamedCapturesOnly is set.
+func (compiled *CompiledGrok) NewRedactor(rules ...RedactRule) (*Redactor, error) {
+	redactor, selected, err := compiled.newRedactor(rules)
+	if err != nil {
+		return nil, err
+	}
+	for i, rule := range rules {
+		if !selected[i] {
+			return nil, fmt.Errorf("redact rule for %s selects no capture", rule.names())
+		}
+	}
+	return redactor, nil
+}
+
+// newRedactor returns a Redactor and whether each rule selects a capture
+func (compiled *CompiledGrok) newRedactor(rules []RedactRule) (*Redactor, []bool, error) {
+	rules = append([]RedactRule(nil), rules...)
+	selected := make([]bool, len(rules))
+	redactor := &Redactor{
+		compiled: compiled,
+		rules:    make([]*RedactRule, len(compiled.groupIdToName)),
+		fields:   make(map[string]*RedactRule),
+	}
+
+	for i := range rules {
+		rule := &rules[i]
+		if rule.Action < RedactDrop || rule.Action > RedactIPPrefix {
+			return nil, nil, fmt.Errorf("unknown redact action %d", int(rule.Action))
+		}
+		if rule.Action == RedactHash && len(rule.Key) == 0 {
+			return nil, nil, errors.New("redact action hash requires a key")
+		}
+
+		for group, name := range compiled.groupIdToName {
+			if len(name) == 0 {
+				continue
+			}
+			if contains(rule.Fields, name) || contains(rule.Patterns, compiled.groupIdToPattern[group]) {
+				selected[i] = true
+				if redactor.rules[group] == nil {
+					redactor.rules[group] = rule
+				}
+			}
+		}
+	}
+
+	// Groups inside a redacted group are redacted the same way, e.g. the
+	// IPV4 group of a redacted IP
+	parents := groupParents(compiled.pattern.expression)
+	hasParents := len(parents) == len(redactor.rules)
+	if hasParents {
+		for group := 1; group < len(parents); group++ {
+			if redactor.rules[group] == nil {
+				redactor.rules[group] = redactor.rules[parents[group]]
+			}
+		}
+	}
+
+	redactor.redacted = make(map[string]bool)
+	for group, rule := range redactor.rules {
+		if rule == nil {
+			continue
+		}
+		redactor.redacted[compiled.groupIdToName[group]] = true
+		for hasParents && group > 0 {
+			group = parents[group]
+			redactor.redacted[compiled.groupIdToName[group]] = true
+		}
+	}
+	delete(redactor.redacted, "")
+
+	// A field can be redacted by name only if all its groups use the same
+	// rule, otherwise values cannot be redacted without knowing their group.
+	partial := make(map[string]bool)
+	for group, name := range compiled.groupIdToName {
+		if len(name) == 0 || partial[name] {
+			continue
+		}
+		rule, known := redactor.fields[name]
+		switch {
+		case !known:
+			redactor.fields[name] = redactor.rules[group]
+		case rule != redactor.rules[group]:
+			partial[name] = true
+			delete(redactor.fields, name)
+		}
+	}
+	for name, rule := range redactor.fields {
+		if rule == nil {
+			delete(redactor.fields, name)
+		}
+	}
+	return redactor, selected, nil
+}
+
+// MatchAgainst works like CompiledGrok.MatchAgainst but returns redacted
+// values. Captures enclosing a redacted capture, e.g. "user" of
+// %{USER:user} if USERNAME is redacted, contain the redacted value. The line
+// is returned with every redacted capture replaced by its redacted value.
+func (redactor *Redactor) MatchAgainst(text string) (bool, map[string]string, string) {
+	return redactor.matchAgainst(redactor.compiled.regexp.NewMatcher(), text)
+}
+
+// matchAgainst implements MatchAgainst using the given matcher
+func (redactor *Redactor) matchAgainst(matcher Matcher, text string) (bool, map[string]string, string) {
+	if !redactor.compiled.matchString(matcher, text) {
+		return false, map[string]string{}, text
+	}
+
+	// Find the outermost redacted groups. Group indices do not follow the
+	// position in the line for alternations or repeated groups, so the spans
+	// are ordered by their start, enclosing spans first.
+	candidates := []redactedSpan{}
+	for group, rule := range redactor.rules {
+		if rule == nil || !matcher.Present(group) {
+			continue
+		}
+		if indices := matcher.GroupIndices(group); indices != nil {
+			candidates = append(candidates, redactedSpan{indices[0], indices[1], rule.redact(text[indices[0]:indices[1]])})
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].start != candidates[j].start {
+			return candidates[i].start < candidates[j].start
+		}
+		return candidates[i].end > candidates[j].end
+	})
+	spans := []redactedSpan{}
+	end := 0
+	for _, span := range candidates {
+		if span.start >= end {
+			spans = append(spans, span)
+			end = span.end
+		}
+	}
+
+	values := make(map[string]string)
+	redacted := make(map[string]bool)
+	for group, name := range redactor.compiled.groupIdToName {
+		if !matcher.Present(group) {
+			continue
+		}
+		indices := matcher.GroupIndices(group)
+		rule := redactor.rules[group]
+		switch {
+		case rule == nil && !redacted[name]:
+			// Enclosing groups contain the redacted values of their groups
+			values[name] = replaceSpans(text, indices[0], indices[1], spans)
+		case rule == nil:
+		case rule.Action == RedactDrop:
+			redacted[name] = true
+			delete(values, name)
+		default:
+			redacted[name] = true
+			values[name] = rule.redact(text[indices[0]:indices[1]])
+		}
+	}
+	return true, values, replaceSpans(text, 0, len(text), spans)
+}
+
+// redactedSpan is a part of a line that is replaced by a redacted value
+type redactedSpan struct {
+	start, end int
+	value      string
+}
+
+// replaceSpans returns text[start:end] with all spans inside of it replaced
+func replaceSpans(text string, start, end int, spans []redactedSpan) string {
+	replaced := strings.Builder{}
+	for _, span := range spans {
+		if span.start < start || span.end > end {
+			continue
+		}
+		replaced.WriteString(text[start:span.start])
+		replaced.WriteString(span.value)
+		start = span.end
+	}
+	replaced.WriteString(text[start:end])
+	return replaced.String()
+}
+
+// Redact applies the rules to values returned by CompiledGrok.MatchAgainst,
+// e.g. by a Pipeline. As the values do not tell which group they were
+// captured by, fields captured by several groups are only redacted if the
+// same rule applies to all of them, and fields enclosing a redacted capture
+// are not changed. Use MatchAgainst to redact these, too. The values are
+// changed in place and returned.
+func (redactor *Redactor) Redact(values map[string]string) map[string]string {
+	for name, rule := range redactor.fields {
+		value, captured := values[name]
+		if !captured {
+			continue
+		}
+		if rule.Action == RedactDrop {
+			delete(values, name)
+		} else {
+			values[name] = rule.redact(value)
+		}
+	}
+	return values
+}
+
+// redacts returns true if the field may contain a redacted value. It
+// returns false for a nil Redactor.
+func (redactor *Redactor) redacts(field string) bool {
+	return redactor != nil && redactor.redacted[field]
+}
+
+// names returns the fields and patterns selected by the rule for messages
+func (rule RedactRule) names() string {
+	names := append([]string{}, rule.Fields...)
+	for _, pattern := range rule.Patterns {
+		if !contains(names, pattern) {
+			names = append(names, pattern)
+		}
+	}
+	return strings.Join(names, ", ")
+}
+
+// redact returns the redacted value
+func (rule *RedactRule) redact(value string) string {
+	switch rule.Action {
+	case RedactMask:
+		return mask(value, rule.Keep)
+
+	case RedactHash:
+		hash := hmac.New(sha256.New, rule.Key)
+		hash.Write([]byte(value))
+		return hex.EncodeToString(hash.Sum(nil))
+
+	case RedactTruncate:
+		if utf8.RuneCountInString(value) <= rule.Keep {
+			return value
+		}
+		return string([]rune(value)[:rule.Keep])
+
+	case RedactIPPrefix:
+		ip := net.ParseIP(value)
+		if ip == nil {
+			return mask(value, 0)
+		}
+		if ipv4 := ip.To4(); ipv4 != nil {
+			return ipv4.Mask(net.CIDRMask(defaultInt(rule.IPv4Bits, 24), 32)).String()
+		}
+		return ip.Mask(net.CIDRMask(defaultInt(rule.IPv6Bits, 48), 128)).String()
+
+	default:
+		return ""
+	}
+}
+
+// groupParents returns the index of the innermost capture group enclosing
+// each capture group of the expression, 0 for groups on the top level
+func groupParents(expression string) []int {
+	parents := []int{0}
+	// enclosing holds the innermost capture group of each open parenthesis
+	enclosing := []int{0}
+	for i := 0; i < len(expression); i++ {
+		switch expression[i] {
+		case '\\':
+			i++
+		case '[':
+			i = classEnd(expression, i) - 1
+		case '(':
+			group := enclosing[len(enclosing)-1]
+			if isCaptureGroup(expression[i:]) {
+				parents = append(parents, group)
+				group = len(parents) - 1
+			}
+			enclosing = append(enclosing, group)
+		case ')':
+			if len(enclosing) > 1 {
+				enclosing = enclosing[:len(enclosing)-1]
+			}
+		}
+	}
+	return parents
+}
+
+// isCaptureGroup returns true if the group starting the expression captures
+func isCaptureGroup(expression string) bool {
+	switch {
+	case !strings.HasPrefix(expression, "(?"):
+		return true
+	case strings.HasPrefix(expression, "(?<="), strings.HasPrefix(expression, "(?<!"):
+		return false
+	default:
+		return strings.HasPrefix(expression, "(?<") || strings.HasPrefix(expression, "(?P<") || strings.HasPrefix(expression, "(?'")
+	}
+}
+
+// mask replaces all but the last keep characters with asterisks
+func mask(value string, keep int) string {
+	runes := []rune(value)
+	for i := 0; i < len(runes)-keep; i++ {
+		runes[i] = '*'
+	}
+	return string(runes)
+}
+
+// defaultInt returns value or fallback if value is not set
+func defaultInt(value, fallback int) int {
+	if value <= 0 {
+		return fallback
+	}
+	return value
+}
+
+// contains returns true if names contains name
+func contains(names []string, name string) bool {
+	for _, candidate := range names {
+		if candidate == name {
+			return true
+		}
+	}
+	return false
+}
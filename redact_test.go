@@ -0,0 +1,115 @@
+package grok
+
+import (
+	"github.com/trivago/tgo/ttesting"
+	"testing"
+)
+
+func TestRedactor(t *testing.T) {
+	expect := ttesting.NewExpect(t)
+
+	g, err := New(Config{NamedCapturesOnly: true})
+	expect.NoError(err)
+	compiled, err := g.Compile(`%{IP:client} %{EMAILADDRESS:from} -> %{EMAILADDRESS:to} %{USERNAME:user} %{WORD:session} %{GREEDYDATA:message}`)
+	expect.NoError(err)
+
+	redactor, err := compiled.NewRedactor(
+		RedactRule{Fields: []string{"client"}, Action: RedactIPPrefix},
+		RedactRule{Patterns: []string{"EMAILADDRESS"}, Action: RedactMask, Keep: 4},
+		RedactRule{Fields: []string{"user"}, Action: RedactHash, Key: []byte("secret")},
+		RedactRule{Fields: []string{"session"}, Action: RedactDrop},
+		RedactRule{Fields: []string{"message"}, Action: RedactTruncate, Keep: 5},
+	)
+	expect.NoError(err)
+
+	hash := "4360c67bc81025114044578d7c4e8e0f02fd0cae99f22d603390e8f9dc9888f8" // HMAC-SHA256 of "alice"
+	matched, values, line := redactor.MatchAgainst("192.168.17.42 alice@example.com -> bob@example.org alice abc123 hello world")
+	expect.True(matched)
+	delete(values, "") // unnamed groups
+	expect.Equal(map[string]string{
+		"client":  "192.168.17.0",
+		"from":    "*************.com",
+		"to":      "***********.org",
+		"user":    hash,
+		"message": "hello",
+	}, values)
+	expect.Equal("192.168.17.0 *************.com -> ***********.org "+hash+"  hello", line)
+
+	// Equal values are hashed to equal values
+	_, other, _ := redactor.MatchAgainst("10.0.0.1 joe@b.c -> amy@e.f alice x y")
+	expect.Equal(hash, other["user"])
+
+	_, plain := compiled.MatchAgainst("10.0.0.1 joe@b.c -> amy@e.f alice x y")
+	delete(plain, "")
+	expect.Equal(map[string]string{
+		"client":  "10.0.0.0",
+		"from":    "***@b.c",
+		"to":      "***@e.f",
+		"user":    hash,
+		"message": "y",
+	}, redactor.Redact(plain))
+
+	_, err = compiled.NewRedactor(RedactRule{Fields: []string{"user"}, Action: RedactHash})
+	expect.NotNil(err)
+	_, err = compiled.NewRedactor(RedactRule{Fields: []string{"usr"}, Patterns: []string{"usr"}, Action: RedactMask})
+	expect.NotNil(err)
+	expect.Equal("redact rule for usr selects no capture", err.Error())
+	// Patterns referenced without a name do not create a capture
+	_, err = compiled.NewRedactor(RedactRule{Patterns: []string{"IPV4"}, Action: RedactMask})
+	expect.NotNil(err)
+}
+
+func TestRedactNestedCaptures(t *testing.T) {
+	expect := ttesting.NewExpect(t)
+
+	g, err := New(Config{})
+	expect.NoError(err)
+	compiled, err := g.Compile(`%{IPORHOST:host} %{USER:user}`)
+	expect.NoError(err)
+
+	redactor, err := compiled.NewRedactor(
+		RedactRule{Patterns: []string{"IPORHOST"}, Action: RedactIPPrefix, IPv6Bits: 32},
+		RedactRule{Patterns: []string{"USERNAME"}, Action: RedactMask},
+	)
+	expect.NoError(err)
+
+	matched, values, line := redactor.MatchAgainst("2001:db8:85a3::8a2e:370:7334 alice")
+	expect.True(matched)
+	expect.Equal("2001:db8::", values["host"])
+	// Captures of the patterns IPORHOST refers to are redacted, too
+	expect.Equal("2001:db8::", values["IPV6"])
+	// Captures enclosing a redacted capture contain the redacted value
+	expect.Equal("*****", values["USERNAME"])
+	expect.Equal("*****", values["user"])
+	expect.Equal("2001:db8:: *****", line)
+
+	matched, values, line = redactor.MatchAgainst("db01.example.com alice")
+	expect.True(matched)
+	expect.Equal("****************", values["host"])
+	expect.Equal("**************** *****", line)
+}
+
+func TestRedactRepeatedCaptures(t *testing.T) {
+	expect := ttesting.NewExpect(t)
+
+	g, err := New(Config{NamedCapturesOnly: true, Patterns: map[string]string{"LETTERS": "[a-z]+"}})
+	expect.NoError(err)
+	compiled, err := g.Compile(`(?:(?:%{LETTERS:word}|%{INT:number}) ?)+`)
+	expect.NoError(err)
+
+	redactor, err := compiled.NewRedactor(RedactRule{Fields: []string{"word", "number"}, Action: RedactMask})
+	expect.NoError(err)
+
+	// word is captured after number although its group comes first
+	matched, values, line := redactor.MatchAgainst("12 ab")
+	expect.True(matched)
+	expect.Equal("**", values["word"])
+	expect.Equal("**", values["number"])
+	expect.Equal("** **", line)
+}
+
+func TestGroupParents(t *testing.T) {
+	expect := ttesting.NewExpect(t)
+
+	expect.Equal([]int{0, 0, 1, 1, 0}, groupParents(`(?<a>x(y)(?:z(?<b>[(]))\))(?<=q)(w)`))
+}
@@ -0,0 +1,264 @@
+package grok
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// defaultReloadInterval is used if ReloaderConfig.Interval is not set
+const defaultReloadInterval = 5 * time.Second
+
+// ReloaderConfig is used to pass a set of configuration values to
+// NewReloader.
+type ReloaderConfig struct {
+	// Grok is the configuration each Grok is built with.
+	Grok Config
+	// Paths are the pattern files or directories to load, see LoadPatterns.
+	// Their definitions replace the default patterns and Grok.Patterns of
+	// the same name, and definitions in later paths replace earlier ones.
+	Paths []string
+	// Interval is the time between two checks of Paths for changes.
+	// Defaults to 5 seconds if not set. If negative, patterns are only
+	// reloaded by Reload.
+	Interval time.Duration
+	// Validate is called with a new snapshot before it replaces the current
+	// one, e.g. to check a corpus with Verify. If an error is returned, the
+	// current snapshot is kept.
+	Validate func(snapshot *ReloadSnapshot) error
+	// OnReload is called after a new snapshot replaced the current one.
+	OnReload func(snapshot *ReloadSnapshot)
+	// OnError is called if reloading after a change of Paths failed. The
+	// current snapshot is kept until the next change.
+	OnError func(err error)
+}
+
+// ReloadSnapshot holds a Grok built by a Reloader and the registered
+// expressions compiled with it. A snapshot is never modified, so lines
+// matched with the same snapshot always see the same patterns.
+type ReloadSnapshot struct {
+	Grok *Grok
+	// Compiled holds the registered expressions by expression
+	Compiled map[string]*CompiledGrok
+	// Loaded is the time the patterns were loaded
+	Loaded time.Time
+}
+
+// Reloader keeps a Grok and a set of registered expressions up to date with
+// pattern files. The files are polled for changes every Interval, there is
+// no file system notification, and a new Grok is built and all expressions
+// are recompiled in the background. The new snapshot replaces the current
+// one atomically through an atomic.Value, as atomic.Pointer needs Go 1.19,
+// so matching is never blocked by a reload. If the files do not load, an
+// expression does not compile anymore or validation fails, the current
+// snapshot is kept.
+// Statistics of Config.CollectStats start over with each snapshot.
+type Reloader struct {
+	config      ReloaderConfig
+	current     atomic.Value // *ReloadSnapshot
+	lock        sync.Mutex
+	expressions []string
+	fingerprint string
+	done        chan struct{}
+	stopped     sync.WaitGroup
+	closeOnce   sync.Once
+}
+
+// NewReloader loads the patterns, compiles the given expressions and starts
+// polling for changes. An error is returned if the initial load fails.
+// Call Close to stop polling.
+func NewReloader(config ReloaderConfig, expressions ...string) (*Reloader, error) {
+	if config.Interval == 0 {
+		config.Interval = defaultReloadInterval
+	}
+	reloader := &Reloader{
+		config:      config,
+		expressions: append([]string{}, expressions...),
+		done:        make(chan struct{}),
+	}
+	if err := reloader.Reload(); err != nil {
+		return nil, err
+	}
+
+	if config.Interval > 0 {
+		reloader.stopped.Add(1)
+		go reloader.poll()
+	}
+	return reloader, nil
+}
+
+// Snapshot returns the current snapshot.
+func (reloader *Reloader) Snapshot() *ReloadSnapshot {
+	return reloader.current.Load().(*ReloadSnapshot)
+}
+
+// Compiled returns the current compilation of a registered expression or
+// nil if the expression is not registered.
+func (reloader *Reloader) Compiled(expression string) *CompiledGrok {
+	return reloader.Snapshot().Compiled[expression]
+}
+
+// Register compiles expressions with the current Grok and recompiles them
+// with every reload from now on. Nothing is registered if one of them does
+// not compile.
+func (reloader *Reloader) Register(expressions ...string) error {
+	reloader.lock.Lock()
+	defer reloader.lock.Unlock()
+
+	current := reloader.Snapshot()
+	snapshot := &ReloadSnapshot{
+		Grok:     current.Grok,
+		Compiled: make(map[string]*CompiledGrok, len(current.Compiled)+len(expressions)),
+		Loaded:   current.Loaded,
+	}
+	for expression, compiled := range current.Compiled {
+		snapshot.Compiled[expression] = compiled
+	}
+	for _, expression := range expressions {
+		compiled, err := current.Grok.Compile(expression)
+		if err != nil {
+			return fmt.Errorf("%s: %s", expression, err)
+		}
+		snapshot.Compiled[expression] = compiled
+	}
+
+	reloader.expressions = append(reloader.expressions, expressions...)
+	reloader.current.Store(snapshot)
+	return nil
+}
+
+// Reload loads the patterns and recompiles all registered expressions now,
+// whether the files changed or not. If an error is returned, the current
+// snapshot is kept.
+func (reloader *Reloader) Reload() error {
+	reloader.lock.Lock()
+	defer reloader.lock.Unlock()
+
+	// Files changing during the load are loaded again by the next check
+	reloader.fingerprint = reloader.scan()
+	return reloader.load()
+}
+
+// Close stops polling for changes. The current snapshot stays usable.
+func (reloader *Reloader) Close() {
+	reloader.closeOnce.Do(func() {
+		close(reloader.done)
+	})
+	reloader.stopped.Wait()
+}
+
+// poll reloads the patterns whenever the files changed
+func (reloader *Reloader) poll() {
+	defer reloader.stopped.Done()
+	ticker := time.NewTicker(reloader.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-reloader.done:
+			return
+		case <-ticker.C:
+			if err := reloader.reloadChanged(); err != nil && reloader.config.OnError != nil {
+				reloader.config.OnError(err)
+			}
+		}
+	}
+}
+
+// reloadChanged reloads the patterns if the files changed since the last
+// attempt. A failed attempt is not repeated until they change again.
+func (reloader *Reloader) reloadChanged() error {
+	reloader.lock.Lock()
+	defer reloader.lock.Unlock()
+
+	fingerprint := reloader.scan()
+	if fingerprint == reloader.fingerprint {
+		return nil
+	}
+	reloader.fingerprint = fingerprint
+	return reloader.load()
+}
+
+// load builds a new snapshot and makes it the current one. The caller must
+// hold the lock.
+func (reloader *Reloader) load() error {
+	loaded := make(map[string]string)
+	for _, path := range reloader.config.Paths {
+		patterns, err := LoadPatterns(path)
+		if err != nil {
+			return err
+		}
+		for name, expression := range patterns {
+			loaded[name] = expression
+		}
+	}
+
+	grok, err := New(reloader.config.Grok.withOverrides(loaded))
+	if err != nil {
+		return err
+	}
+	snapshot := &ReloadSnapshot{
+		Grok:     grok,
+		Compiled: make(map[string]*CompiledGrok, len(reloader.expressions)),
+		Loaded:   time.Now(),
+	}
+	for _, expression := range reloader.expressions {
+		compiled, err := grok.Compile(expression)
+		if err != nil {
+			return fmt.Errorf("%s: %s", expression, err)
+		}
+		snapshot.Compiled[expression] = compiled
+	}
+
+	if reloader.config.Validate != nil {
+		if err := reloader.config.Validate(snapshot); err != nil {
+			return err
+		}
+	}
+	reloader.current.Store(snapshot)
+	if reloader.config.OnReload != nil {
+		reloader.config.OnReload(snapshot)
+	}
+	return nil
+}
+
+// scan returns the names, sizes and modification times of all pattern
+// files. Paths that cannot be read are listed as missing, so that their
+// creation is noticed.
+func (reloader *Reloader) scan() string {
+	fingerprint := strings.Builder{}
+	add := func(path string, info os.FileInfo) {
+		fmt.Fprintf(&fingerprint, "%s %d %d\n", path, info.Size(), info.ModTime().UnixNano())
+	}
+
+	for _, path := range reloader.config.Paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			fmt.Fprintf(&fingerprint, "%s missing\n", path)
+			continue
+		}
+		if !info.IsDir() {
+			add(path, info)
+			continue
+		}
+
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			fmt.Fprintf(&fingerprint, "%s missing\n", path)
+			continue
+		}
+		for _, entry := range entries {
+			if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+				continue
+			}
+			if info, err := entry.Info(); err == nil {
+				add(filepath.Join(path, entry.Name()), info)
+			}
+		}
+	}
+	return fingerprint.String()
+}
@@ -0,0 +1,124 @@
+package grok
+
+import (
+	"errors"
+	"github.com/trivago/tgo/ttesting"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// waitFor polls condition until it is true or fails after a timeout
+func waitFor(t *testing.T, condition func() bool) {
+	deadline := time.Now().Add(5 * time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestReloader(t *testing.T) {
+	expect := ttesting.NewExpect(t)
+	dir := t.TempDir()
+	path := filepath.Join(dir, "greetings")
+	expect.NoError(os.WriteFile(path, []byte("GREETING hello\n"), 0644))
+
+	errs := make(chan error, 4)
+	reloader, err := NewReloader(ReloaderConfig{
+		Grok:     Config{NamedCapturesOnly: true},
+		Paths:    []string{dir},
+		Interval: 5 * time.Millisecond,
+		OnError:  func(err error) { errs <- err },
+	}, "%{GREETING:greeting} %{WORD:name}")
+	expect.NoError(err)
+	defer reloader.Close()
+
+	compiled := reloader.Compiled("%{GREETING:greeting} %{WORD:name}")
+	expect.True(compiled.MatchString("hello world"))
+	expect.False(compiled.MatchString("goodbye world"))
+	expect.Nil(reloader.Compiled("%{GREETING}"))
+
+	expect.NoError(os.WriteFile(path, []byte("GREETING hello|goodbye\n"), 0644))
+	waitFor(t, func() bool {
+		return reloader.Compiled("%{GREETING:greeting} %{WORD:name}").MatchString("goodbye world")
+	})
+	loaded := reloader.Snapshot().Loaded
+
+	// The current snapshot is kept if the patterns break
+	expect.NoError(os.WriteFile(path, []byte("GREETING %{MISSING}\n"), 0644))
+	select {
+	case err := <-errs:
+		expect.NotNil(err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("no error reported")
+	}
+	expect.Equal(loaded, reloader.Snapshot().Loaded)
+	expect.True(reloader.Compiled("%{GREETING:greeting} %{WORD:name}").MatchString("goodbye world"))
+
+	// New files in the directory are picked up
+	expect.NoError(os.WriteFile(path, []byte("GREETING hi\n"), 0644))
+	expect.NoError(os.WriteFile(filepath.Join(dir, "names"), []byte("NAME amy|bob\n"), 0644))
+	waitFor(t, func() bool {
+		_, known := reloader.Snapshot().Grok.Definition("NAME")
+		return known
+	})
+	expect.NoError(reloader.Register("%{GREETING} %{NAME:name}"))
+	expect.True(reloader.Compiled("%{GREETING} %{NAME:name}").MatchString("hi amy"))
+	expect.NotNil(reloader.Register("%{UNKNOWN}"))
+	expect.Nil(reloader.Compiled("%{UNKNOWN}"))
+}
+
+func TestReloaderValidate(t *testing.T) {
+	expect := ttesting.NewExpect(t)
+	path := filepath.Join(t.TempDir(), "patterns")
+	expect.NoError(os.WriteFile(path, []byte("STATUS ok\n"), 0644))
+
+	reloads := 0
+	reloader, err := NewReloader(ReloaderConfig{
+		Grok:     Config{Patterns: map[string]string{"STATUS": "unused", "CODE": `\d+`}},
+		Paths:    []string{path},
+		Interval: -1,
+		Validate: func(snapshot *ReloadSnapshot) error {
+			if !snapshot.Compiled["%{STATUS} %{CODE}"].MatchString("ok 200") {
+				return errors.New("ok 200 does not match")
+			}
+			return nil
+		},
+		OnReload: func(snapshot *ReloadSnapshot) { reloads++ },
+	}, "%{STATUS} %{CODE}")
+	expect.NoError(err)
+	expect.Equal(1, reloads)
+
+	expect.NoError(os.WriteFile(path, []byte("STATUS failed\n"), 0644))
+	expect.NotNil(reloader.Reload())
+	expect.Equal(1, reloads)
+	expect.True(reloader.Compiled("%{STATUS} %{CODE}").MatchString("ok 200"))
+
+	expect.NoError(os.WriteFile(path, []byte("STATUS ok|failed\n"), 0644))
+	expect.NoError(reloader.Reload())
+	expect.Equal(2, reloads)
+	expect.True(reloader.Compiled("%{STATUS} %{CODE}").MatchString("failed 500"))
+	reloader.Close()
+
+	_, err = NewReloader(ReloaderConfig{Paths: []string{filepath.Join(t.TempDir(), "missing")}})
+	expect.NotNil(err)
+}
+
+func TestReloaderOverridesDefaults(t *testing.T) {
+	expect := ttesting.NewExpect(t)
+	path := filepath.Join(t.TempDir(), "patterns")
+	expect.NoError(os.WriteFile(path, []byte("WORD [a-z]+!\n"), 0644))
+
+	reloader, err := NewReloader(ReloaderConfig{Paths: []string{path}, Interval: -1}, "^%{WORD} %{WORD}$")
+	expect.NoError(err)
+	defer reloader.Close()
+	expect.True(reloader.Compiled("^%{WORD} %{WORD}$").MatchString("hello! world!"))
+	expect.False(reloader.Compiled("^%{WORD} %{WORD}$").MatchString("hello world"))
+
+	expect.NoError(os.WriteFile(path, []byte("WORD [a-z]+\n"), 0644))
+	expect.NoError(reloader.Reload())
+	expect.True(reloader.Compiled("^%{WORD} %{WORD}$").MatchString("hello world"))
+}
@@ -0,0 +1,224 @@
+package grok
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// LatencyBuckets are the upper bounds of the latency histogram of
+// MatchStats. It must not be changed once expressions are compiled with
+// Config.CollectStats.
+var LatencyBuckets = []time.Duration{
+	time.Microsecond,
+	5 * time.Microsecond,
+	10 * time.Microsecond,
+	50 * time.Microsecond,
+	100 * time.Microsecond,
+	500 * time.Microsecond,
+	time.Millisecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+}
+
+// MatchStats is a snapshot of the statistics recorded for an expression
+// compiled with Config.CollectStats.
+type MatchStats struct {
+	// Name is the expression or the name it was added to a PatternSet with
+	Name    string
+	Matches uint64
+	Misses  uint64
+	// LimitHits counts the matches aborted because they exceeded the
+	// backtracking limit of the engine, see LimitReporter. They are counted
+	// as misses, too.
+	LimitHits uint64
+	// Latency counts the matches by duration. Latency[i] counts the ones
+	// that took at most LatencyBuckets[i], the last element the slower ones.
+	Latency    []uint64
+	LatencySum time.Duration
+	// Captures counts the matches the fields were extracted for, e.g. by
+	// MatchAgainst. EmptyFields counts by field name how many of these did
+	// not capture the field or captured an empty value.
+	Captures    uint64
+	EmptyFields map[string]uint64
+}
+
+// MatchRate returns the ratio of matching lines, 0 if nothing was matched
+// yet.
+func (stats MatchStats) MatchRate() float64 {
+	if total := stats.Matches + stats.Misses; total > 0 {
+		return float64(stats.Matches) / float64(total)
+	}
+	return 0
+}
+
+// EmptyRate returns the ratio of captures that did not capture the given
+// field or captured an empty value.
+func (stats MatchStats) EmptyRate(field string) float64 {
+	if stats.Captures == 0 {
+		return 0
+	}
+	return float64(stats.EmptyFields[field]) / float64(stats.Captures)
+}
+
+// statsCollector records the statistics of a CompiledGrok. It is updated
+// atomically, so it can be shared by all copies of the CompiledGrok.
+type statsCollector struct {
+	matches    uint64
+	misses     uint64
+	limitHits  uint64
+	captures   uint64
+	latencySum int64
+	latency    []uint64
+	fields     []string
+	empty      []uint64
+}
+
+func newStatsCollector(fields []string) *statsCollector {
+	return &statsCollector{
+		latency: make([]uint64, len(LatencyBuckets)+1),
+		fields:  fields,
+		empty:   make([]uint64, len(fields)),
+	}
+}
+
+// record adds a match. Values are nil if no fields were extracted.
+func (stats *statsCollector) record(matcher Matcher, matched bool, duration time.Duration, values map[string]string) {
+	if matched {
+		atomic.AddUint64(&stats.matches, 1)
+	} else {
+		atomic.AddUint64(&stats.misses, 1)
+		if limited, isReporter := matcher.(LimitReporter); isReporter && limited.LimitExceeded() {
+			atomic.AddUint64(&stats.limitHits, 1)
+		}
+	}
+
+	bucket := sort.Search(len(LatencyBuckets), func(i int) bool {
+		return duration <= LatencyBuckets[i]
+	})
+	atomic.AddUint64(&stats.latency[bucket], 1)
+	atomic.AddInt64(&stats.latencySum, int64(duration))
+
+	if matched && values != nil {
+		atomic.AddUint64(&stats.captures, 1)
+		for i, field := range stats.fields {
+			if len(values[field]) == 0 {
+				atomic.AddUint64(&stats.empty[i], 1)
+			}
+		}
+	}
+}
+
+// snapshot returns the current statistics
+func (stats *statsCollector) snapshot(name string) MatchStats {
+	snapshot := MatchStats{
+		Name:        name,
+		Matches:     atomic.LoadUint64(&stats.matches),
+		Misses:      atomic.LoadUint64(&stats.misses),
+		LimitHits:   atomic.LoadUint64(&stats.limitHits),
+		Latency:     make([]uint64, len(stats.latency)),
+		LatencySum:  time.Duration(atomic.LoadInt64(&stats.latencySum)),
+		Captures:    atomic.LoadUint64(&stats.captures),
+		EmptyFields: make(map[string]uint64, len(stats.fields)),
+	}
+	for i := range stats.latency {
+		snapshot.Latency[i] = atomic.LoadUint64(&stats.latency[i])
+	}
+	for i, field := range stats.fields {
+		snapshot.EmptyFields[field] = atomic.LoadUint64(&stats.empty[i])
+	}
+	return snapshot
+}
+
+// Stats returns the statistics recorded for this expression. Nothing is
+// recorded unless it was compiled with Config.CollectStats.
+func (compiled CompiledGrok) Stats() MatchStats {
+	if compiled.stats == nil {
+		return MatchStats{Name: compiled.String()}
+	}
+	return compiled.stats.snapshot(compiled.String())
+}
+
+// Stats returns the statistics of each expression of the set in the order
+// they are tried. The misses of the last expression are the lines no
+// expression matched.
+func (set *PatternSet) Stats() []MatchStats {
+	stats := make([]MatchStats, len(set.compiled))
+	for i, compiled := range set.compiled {
+		stats[i] = compiled.Stats()
+		stats[i].Name = set.names[i]
+	}
+	return stats
+}
+
+// WritePrometheus writes statistics in the Prometheus text format. Each
+// expression is labeled with its name as "pattern".
+func WritePrometheus(out io.Writer, stats []MatchStats) error {
+	writer := bufio.NewWriter(out)
+	counter := func(metric, help string, value func(MatchStats) uint64) {
+		fmt.Fprintf(writer, "# HELP %s %s\n# TYPE %s counter\n", metric, help, metric)
+		for _, pattern := range stats {
+			fmt.Fprintf(writer, "%s{pattern=%s} %d\n", metric, prometheusLabel(pattern.Name), value(pattern))
+		}
+	}
+
+	counter("grok_matches_total", "Number of lines matching the expression.",
+		func(pattern MatchStats) uint64 { return pattern.Matches })
+	counter("grok_misses_total", "Number of lines not matching the expression.",
+		func(pattern MatchStats) uint64 { return pattern.Misses })
+	counter("grok_match_limit_hits_total", "Number of matches aborted by the backtracking limit of the engine.",
+		func(pattern MatchStats) uint64 { return pattern.LimitHits })
+	counter("grok_captures_total", "Number of matches the fields were extracted for.",
+		func(pattern MatchStats) uint64 { return pattern.Captures })
+
+	fmt.Fprint(writer, "# HELP grok_match_duration_seconds Time spent matching a line.\n# TYPE grok_match_duration_seconds histogram\n")
+	for _, pattern := range stats {
+		label := prometheusLabel(pattern.Name)
+		total := uint64(0)
+		for i, count := range pattern.Latency {
+			total += count
+			bound := "+Inf"
+			if i < len(LatencyBuckets) {
+				bound = strconv.FormatFloat(LatencyBuckets[i].Seconds(), 'g', -1, 64)
+			}
+			fmt.Fprintf(writer, "grok_match_duration_seconds_bucket{pattern=%s,le=\"%s\"} %d\n", label, bound, total)
+		}
+		fmt.Fprintf(writer, "grok_match_duration_seconds_sum{pattern=%s} %s\n", label, strconv.FormatFloat(pattern.LatencySum.Seconds(), 'g', -1, 64))
+		fmt.Fprintf(writer, "grok_match_duration_seconds_count{pattern=%s} %d\n", label, total)
+	}
+
+	fmt.Fprint(writer, "# HELP grok_field_empty_total Number of captures with an empty or missing field.\n# TYPE grok_field_empty_total counter\n")
+	for _, pattern := range stats {
+		fields := make([]string, 0, len(pattern.EmptyFields))
+		for field := range pattern.EmptyFields {
+			fields = append(fields, field)
+		}
+		sort.Strings(fields)
+		for _, field := range fields {
+			fmt.Fprintf(writer, "grok_field_empty_total{pattern=%s,field=%s} %d\n", prometheusLabel(pattern.Name), prometheusLabel(field), pattern.EmptyFields[field])
+		}
+	}
+	return writer.Flush()
+}
+
+// PrometheusHandler returns a handler serving the statistics returned by
+// snapshot in the Prometheus text format, e.g. PatternSet.Stats.
+func PrometheusHandler(snapshot func() []MatchStats) http.Handler {
+	return http.HandlerFunc(func(response http.ResponseWriter, request *http.Request) {
+		response.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		WritePrometheus(response, snapshot())
+	})
+}
+
+// prometheusLabel returns a quoted label value
+func prometheusLabel(value string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value) + `"`
+}
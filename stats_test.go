@@ -0,0 +1,131 @@
+package grok
+
+import (
+	"bytes"
+	"github.com/trivago/tgo/ttesting"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// limitedEngine reports every subject starting with "limit" as exceeding the
+// match limit
+type limitedEngine struct{}
+
+type limitedRegexp struct {
+	Regexp
+}
+
+type limitedMatcher struct {
+	Matcher
+	limited bool
+}
+
+func (engine limitedEngine) Name() string {
+	return "limited"
+}
+
+func (engine limitedEngine) Compile(expression string) (Regexp, error) {
+	compiled, err := RE2.Compile(expression)
+	return limitedRegexp{compiled}, err
+}
+
+func (re limitedRegexp) NewMatcher() Matcher {
+	return &limitedMatcher{Matcher: re.Regexp.NewMatcher()}
+}
+
+func (m *limitedMatcher) MatchString(subject string) bool {
+	m.limited = strings.HasPrefix(subject, "limit")
+	return !m.limited && m.Matcher.MatchString(subject)
+}
+
+func (m *limitedMatcher) LimitExceeded() bool {
+	return m.limited
+}
+
+func TestStats(t *testing.T) {
+	expect := ttesting.NewExpect(t)
+	g, _ := New(Config{NamedCapturesOnly: true, CollectStats: true, Engine: limitedEngine{}})
+
+	compiled, err := g.Compile("%{WORD:verb}(?: %{NUMBER:bytes})?")
+	expect.NoError(err)
+
+	compiled.MatchAgainst("GET 10")
+	compiled.MatchAgainst("GET")
+	compiled.MatchAgainst("-")
+	compiled.MatchAgainst("limit")
+	expect.True(compiled.MatchString("POST"))
+
+	stats := compiled.Stats()
+	expect.Equal("%{WORD:verb}(?: %{NUMBER:bytes})?", stats.Name)
+	expect.Equal(uint64(3), stats.Matches)
+	expect.Equal(uint64(2), stats.Misses)
+	expect.Equal(uint64(1), stats.LimitHits)
+	expect.Equal(uint64(2), stats.Captures)
+	expect.Equal(map[string]uint64{"verb": 0, "bytes": 1}, stats.EmptyFields)
+	expect.Equal(0.5, stats.EmptyRate("bytes"))
+	expect.Equal(0.6, stats.MatchRate())
+	expect.Equal(len(LatencyBuckets)+1, len(stats.Latency))
+
+	total := uint64(0)
+	for _, count := range stats.Latency {
+		total += count
+	}
+	expect.Equal(uint64(5), total)
+
+	// Nothing is recorded without CollectStats
+	g, _ = New(Config{})
+	compiled, _ = g.Compile("%{WORD:verb}")
+	compiled.MatchAgainst("GET")
+	expect.Equal(MatchStats{Name: "%{WORD:verb}"}, compiled.Stats())
+}
+
+func TestPatternSetStats(t *testing.T) {
+	expect := ttesting.NewExpect(t)
+	g, _ := New(Config{NamedCapturesOnly: true, CollectStats: true})
+
+	set, err := g.CompilePatternSet("%{INT:number}", "%{WORD:word}")
+	expect.NoError(err)
+	for _, line := range []string{"1", "a", "-", "2"} {
+		set.MatchAgainst(line)
+	}
+
+	stats := set.Stats()
+	expect.Equal(2, len(stats))
+	expect.Equal("%{INT:number}", stats[0].Name)
+	expect.Equal(uint64(2), stats[0].Matches)
+	expect.Equal(uint64(2), stats[0].Misses)
+	expect.Equal(uint64(1), stats[1].Matches)
+	expect.Equal(uint64(1), stats[1].Misses)
+}
+
+func TestWritePrometheus(t *testing.T) {
+	expect := ttesting.NewExpect(t)
+
+	stats := []MatchStats{{
+		Name:        `%{QS:"quoted"}`,
+		Matches:     3,
+		Misses:      1,
+		Latency:     append(make([]uint64, len(LatencyBuckets)), 4),
+		Captures:    3,
+		EmptyFields: map[string]uint64{"quoted": 2},
+	}}
+	stats[0].Latency[0] = 1
+
+	out := bytes.Buffer{}
+	expect.NoError(WritePrometheus(&out, stats))
+	text := out.String()
+	expect.True(strings.Contains(text, "# TYPE grok_matches_total counter\n"))
+	expect.True(strings.Contains(text, `grok_matches_total{pattern="%{QS:\"quoted\"}"} 3`))
+	expect.True(strings.Contains(text, `grok_misses_total{pattern="%{QS:\"quoted\"}"} 1`))
+	expect.True(strings.Contains(text, `grok_match_duration_seconds_bucket{pattern="%{QS:\"quoted\"}",le="1e-06"} 1`))
+	expect.True(strings.Contains(text, `grok_match_duration_seconds_bucket{pattern="%{QS:\"quoted\"}",le="0.1"} 1`))
+	expect.True(strings.Contains(text, `grok_match_duration_seconds_bucket{pattern="%{QS:\"quoted\"}",le="+Inf"} 5`))
+	expect.True(strings.Contains(text, `grok_match_duration_seconds_count{pattern="%{QS:\"quoted\"}"} 5`))
+	expect.True(strings.Contains(text, `grok_field_empty_total{pattern="%{QS:\"quoted\"}",field="quoted"} 2`))
+
+	recorder := httptest.NewRecorder()
+	PrometheusHandler(func() []MatchStats { return stats }).ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+	expect.Equal(text, recorder.Body.String())
+	expect.True(strings.HasPrefix(recorder.Header().Get("Content-Type"), "text/plain"))
+}
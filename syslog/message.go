@@ -0,0 +1,111 @@
+package syslog
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// trimMessage removes the line endings and NUL bytes some senders add to
+// messages
+func trimMessage(message []byte) string {
+	return strings.TrimRight(string(message), "\r\n\x00")
+}
+
+// priority decodes the facility and severity of a message starting with a
+// priority like "<34>". -1 is returned for both if there is none.
+func priority(message string) (int, int) {
+	end := strings.IndexByte(message, '>')
+	if len(message) < 3 || message[0] != '<' || end < 2 || end > 4 {
+		return -1, -1
+	}
+	value, err := strconv.Atoi(message[1:end])
+	if err != nil || value < 0 || value > 191 {
+		return -1, -1
+	}
+	return value / 8, value % 8
+}
+
+// frameReader splits a stream into messages, see RFC 6587. Each message is
+// either prefixed by its length ("octet counting") or terminated by a
+// newline.
+type frameReader struct {
+	reader  *bufio.Reader
+	maxSize int
+}
+
+// maxLengthDigits is the maximum number of digits of an octet count
+const maxLengthDigits = 10
+
+var (
+	// errFrameTooLarge is returned for octet counted messages exceeding the
+	// maximum message size
+	errFrameTooLarge = errors.New("syslog: message exceeds maximum size")
+	// errInvalidLength is returned for octet counts with too many digits
+	errInvalidLength = errors.New("syslog: invalid message length")
+)
+
+func newFrameReader(reader io.Reader, maxSize int) *frameReader {
+	return &frameReader{
+		reader:  bufio.NewReaderSize(reader, maxSize),
+		maxSize: maxSize,
+	}
+}
+
+// next returns the next message of the stream. A message may be returned
+// together with an error if the stream ended without a newline. Messages
+// are octet counted if they start with a length and a space, otherwise they
+// are read up to the next newline.
+func (frames *frameReader) next() ([]byte, error) {
+	for digits := 0; ; digits++ {
+		prefix, err := frames.reader.Peek(digits + 1)
+		if len(prefix) <= digits {
+			if digits == 0 {
+				return nil, err
+			}
+			return frames.nextLine()
+		}
+
+		switch char := prefix[digits]; {
+		case char == ' ' && digits > 0:
+			return frames.nextCounted(string(prefix[:digits]))
+		case char >= '1' && char <= '9', char == '0' && digits > 0:
+			if digits == maxLengthDigits {
+				return nil, errInvalidLength
+			}
+		default:
+			return frames.nextLine()
+		}
+	}
+}
+
+// nextCounted reads a message prefixed by the given length and a space
+func (frames *frameReader) nextCounted(prefix string) ([]byte, error) {
+	length, err := strconv.Atoi(prefix)
+	if err != nil {
+		return nil, errInvalidLength
+	}
+	if length > frames.maxSize {
+		return nil, errFrameTooLarge
+	}
+	frames.reader.Discard(len(prefix) + 1)
+
+	message := make([]byte, length)
+	if _, err := io.ReadFull(frames.reader, message); err != nil {
+		return nil, err
+	}
+	return message, nil
+}
+
+// nextLine reads a message terminated by a newline. Messages longer than the
+// maximum size are truncated.
+func (frames *frameReader) nextLine() ([]byte, error) {
+	line, err := frames.reader.ReadSlice('\n')
+	message := append([]byte(nil), line...)
+	for err == bufio.ErrBufferFull {
+		_, err = frames.reader.ReadSlice('\n')
+	}
+	return message, err
+}
@@ -0,0 +1,64 @@
+package syslog
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/trivago/tgo/ttesting"
+)
+
+func TestFrameReader(t *testing.T) {
+	expect := ttesting.NewExpect(t)
+
+	frames := newFrameReader(strings.NewReader("5 hello2019-03-01T10:00:00Z app: up\n12345\n0 zero\n7"), 64)
+	message, err := frames.next()
+	expect.NoError(err)
+	expect.Equal("hello", string(message))
+
+	// Lines starting with digits but no length are newline framed
+	message, err = frames.next()
+	expect.NoError(err)
+	expect.Equal("2019-03-01T10:00:00Z app: up\n", string(message))
+	message, err = frames.next()
+	expect.NoError(err)
+	expect.Equal("12345\n", string(message))
+	message, err = frames.next()
+	expect.NoError(err)
+	expect.Equal("0 zero\n", string(message))
+
+	message, err = frames.next()
+	expect.Equal(io.EOF, err)
+	expect.Equal("7", string(message))
+
+	_, err = newFrameReader(strings.NewReader("100 too long"), 64).next()
+	expect.Equal(errFrameTooLarge, err)
+}
+
+func TestFrameReaderLengthLimit(t *testing.T) {
+	expect := ttesting.NewExpect(t)
+
+	// A peer sending digits without a space must not be buffered forever
+	reader, writer := io.Pipe()
+	go func() {
+		for {
+			if _, err := writer.Write([]byte("1111111111")); err != nil {
+				return
+			}
+		}
+	}()
+	defer reader.Close()
+
+	result := make(chan error, 1)
+	go func() {
+		_, err := newFrameReader(reader, 64).next()
+		result <- err
+	}()
+	select {
+	case err := <-result:
+		expect.Equal(errInvalidLength, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("length is read without limit")
+	}
+}
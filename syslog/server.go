@@ -0,0 +1,337 @@
+// Package syslog receives syslog messages on UDP, TCP, TLS and Unix sockets
+// and parses them with a grok.PatternSet.
+package syslog
+
+import (
+	"crypto/tls"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/rtkjweeks/grok-go-pcre"
+	"github.com/rtkjweeks/grok-go-pcre/patterns"
+)
+
+// DefaultExpressions parse RFC 5424 and RFC 3164 messages. They are tried
+// after Config.Expressions.
+var DefaultExpressions = []string{
+	"%{SYSLOG5424LINE}",
+	"(?:%{SYSLOG5424PRI})?%{SYSLOGLINE}",
+}
+
+// Config is used to pass a set of configuration values to NewServer.
+type Config struct {
+	// Grok configures the grok instance parsing the messages.
+	// patterns.LinuxSyslog replaces the default patterns of the same name,
+	// and patterns given in Grok.Patterns replace both.
+	Grok grok.Config
+	// Expressions are tried in order before DefaultExpressions
+	Expressions []string
+	// MaxMessageSize is the number of bytes of a message, 64 KiB by default.
+	// Longer datagrams and newline terminated messages are truncated, stream
+	// connections sending longer octet counted messages, or a length of more
+	// than 10 digits, are closed.
+	MaxMessageSize int
+}
+
+// Record is a received message
+type Record struct {
+	Received time.Time
+	// Remote is the address of the sender, empty for Unix sockets
+	Remote  string
+	Message string
+	// Facility and Severity are decoded from the priority of the message,
+	// -1 if the message has no valid priority
+	Facility int
+	Severity int
+	// Pattern is the expression that matched, empty if none did
+	Pattern string
+	// Fields holds the named captures of Pattern
+	Fields map[string]string
+}
+
+// Handler is called for each received message. It is called concurrently by
+// the goroutines serving the sockets and connections.
+type Handler func(record Record)
+
+// Server parses the messages received by its listeners and passes them to a
+// Handler.
+type Server struct {
+	set     *grok.PatternSet
+	handler Handler
+	maxSize int
+
+	lock    sync.Mutex
+	closers map[io.Closer]bool
+	closed  bool
+	serving sync.WaitGroup
+}
+
+// ErrServerClosed is returned when listening on a closed server.
+var ErrServerClosed = errors.New("syslog: server is closed")
+
+// NewServer compiles the expressions of the config. Call one of the Listen
+// functions to receive messages.
+func NewServer(config Config, handler Handler) (*Server, error) {
+	defaults := grok.DefaultPatterns
+	if config.Grok.ECSCompatibility {
+		defaults = grok.ECSDefaultPatterns
+	}
+	if config.Grok.SkipDefaultPatterns {
+		defaults = nil
+	}
+
+	// The defaults are skipped, as they would take precedence over patterns
+	// of the same name
+	grokConfig := config.Grok
+	grokConfig.SkipDefaultPatterns = true
+	grokConfig.Patterns = make(map[string]string, len(defaults)+len(patterns.LinuxSyslog)+len(config.Grok.Patterns))
+	for _, definitions := range []map[string]string{defaults, patterns.LinuxSyslog, config.Grok.Patterns} {
+		for name, pattern := range definitions {
+			grokConfig.Patterns[name] = pattern
+		}
+	}
+
+	g, err := grok.New(grokConfig)
+	if err != nil {
+		return nil, err
+	}
+	expressions := append(append([]string(nil), config.Expressions...), DefaultExpressions...)
+	set, err := g.CompilePatternSet(expressions...)
+	if err != nil {
+		return nil, err
+	}
+
+	maxSize := config.MaxMessageSize
+	if maxSize <= 0 {
+		maxSize = 64 * 1024
+	}
+	return &Server{
+		set:     set,
+		handler: handler,
+		maxSize: maxSize,
+		closers: make(map[io.Closer]bool),
+	}, nil
+}
+
+// Parse returns the record of a single message without its framing.
+func (server *Server) Parse(message []byte, remote string) Record {
+	text := trimMessage(message)
+	facility, severity := priority(text)
+	_, fields, pattern := server.set.MatchAgainst(text)
+	delete(fields, "")
+
+	return Record{
+		Received: time.Now(),
+		Remote:   remote,
+		Message:  text,
+		Facility: facility,
+		Severity: severity,
+		Pattern:  pattern,
+		Fields:   fields,
+	}
+}
+
+// Stats returns the match statistics of the expressions in the order they
+// are tried. Statistics are only recorded if Config.Grok.CollectStats is set.
+func (server *Server) Stats() []grok.MatchStats {
+	return server.set.Stats()
+}
+
+// ListenUDP receives one message per datagram on the given address. The
+// address listened on is returned, e.g. to find the port chosen for ":0".
+func (server *Server) ListenUDP(address string) (net.Addr, error) {
+	conn, err := net.ListenPacket("udp", address)
+	if err != nil {
+		return nil, err
+	}
+	if err := server.servePackets(conn); err != nil {
+		return nil, err
+	}
+	return conn.LocalAddr(), nil
+}
+
+// ListenTCP receives messages framed by octet counting or newlines as
+// described in RFC 6587. The connections use TLS if tlsConfig is set. The
+// address listened on is returned.
+func (server *Server) ListenTCP(address string, tlsConfig *tls.Config) (net.Addr, error) {
+	var listener net.Listener
+	var err error
+	if tlsConfig != nil {
+		listener, err = tls.Listen("tcp", address, tlsConfig)
+	} else {
+		listener, err = net.Listen("tcp", address)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := server.serveStreams(listener); err != nil {
+		return nil, err
+	}
+	return listener.Addr(), nil
+}
+
+// ListenUnix receives messages on a Unix socket, e.g. /dev/log. The network
+// is "unixgram" for one message per datagram or "unix" for connections
+// framed like ListenTCP.
+func (server *Server) ListenUnix(network, path string) error {
+	switch network {
+	case "unixgram":
+		conn, err := net.ListenPacket(network, path)
+		if err != nil {
+			return err
+		}
+		return server.servePackets(conn)
+	case "unix":
+		listener, err := net.Listen(network, path)
+		if err != nil {
+			return err
+		}
+		return server.serveStreams(listener)
+	default:
+		return net.UnknownNetworkError(network)
+	}
+}
+
+// Close stops all listeners and connections and waits until the handlers of
+// messages being processed returned.
+func (server *Server) Close() error {
+	server.lock.Lock()
+	server.closed = true
+	var err error
+	for closer := range server.closers {
+		if closeErr := closer.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}
+	server.lock.Unlock()
+
+	server.serving.Wait()
+	return err
+}
+
+// track registers a listener or connection to be closed by Close. False is
+// returned if the server is already closed.
+func (server *Server) track(closer io.Closer) bool {
+	server.lock.Lock()
+	defer server.lock.Unlock()
+	if server.closed {
+		closer.Close()
+		return false
+	}
+	server.closers[closer] = true
+	server.serving.Add(1)
+	return true
+}
+
+// untrack removes a listener or connection registered by track
+func (server *Server) untrack(closer io.Closer) {
+	server.lock.Lock()
+	delete(server.closers, closer)
+	server.lock.Unlock()
+	closer.Close()
+	server.serving.Done()
+}
+
+// servePackets handles the datagrams of a packet socket in the background
+func (server *Server) servePackets(conn net.PacketConn) error {
+	if !server.track(conn) {
+		return ErrServerClosed
+	}
+
+	go func() {
+		defer server.untrack(conn)
+		buffer := make([]byte, server.maxSize)
+		var delay time.Duration
+		for {
+			n, addr, err := conn.ReadFrom(buffer)
+			if err != nil {
+				if isTemporary(err) {
+					delay = backoff(delay)
+					time.Sleep(delay)
+					continue
+				}
+				return
+			}
+			delay = 0
+			server.handler(server.Parse(buffer[:n], remoteAddress(addr)))
+		}
+	}()
+	return nil
+}
+
+// serveStreams accepts connections of a stream socket in the background
+func (server *Server) serveStreams(listener net.Listener) error {
+	if !server.track(listener) {
+		return ErrServerClosed
+	}
+
+	go func() {
+		defer server.untrack(listener)
+		var delay time.Duration
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				if isTemporary(err) {
+					delay = backoff(delay)
+					time.Sleep(delay)
+					continue
+				}
+				return
+			}
+			delay = 0
+			if server.track(conn) {
+				go server.serveStream(conn)
+			}
+		}
+	}()
+	return nil
+}
+
+// serveStream handles the messages of a single connection
+func (server *Server) serveStream(conn net.Conn) {
+	defer server.untrack(conn)
+	remote := remoteAddress(conn.RemoteAddr())
+	frames := newFrameReader(conn, server.maxSize)
+	for {
+		message, err := frames.next()
+		if len(message) > 0 {
+			server.handler(server.Parse(message, remote))
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// remoteAddress returns the address of a sender, empty for unnamed Unix
+// sockets
+func remoteAddress(addr net.Addr) string {
+	if addr == nil {
+		return ""
+	}
+	if _, isUnix := addr.(*net.UnixAddr); isUnix {
+		return ""
+	}
+	return addr.String()
+}
+
+// isTemporary returns true if accepting or reading may be retried
+func isTemporary(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Temporary()
+}
+
+// backoff returns how long to wait before retrying after a temporary error,
+// doubling the last delay from 5ms up to 1s like net/http does
+func backoff(delay time.Duration) time.Duration {
+	if delay == 0 {
+		return 5 * time.Millisecond
+	}
+	if delay *= 2; delay > time.Second {
+		return time.Second
+	}
+	return delay
+}
@@ -0,0 +1,166 @@
+package syslog
+
+import (
+	"net"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rtkjweeks/grok-go-pcre"
+	"github.com/trivago/tgo/ttesting"
+)
+
+// collect returns a handler sending records to a channel
+func collect() (Handler, chan Record) {
+	records := make(chan Record, 16)
+	return func(record Record) { records <- record }, records
+}
+
+// receive returns the next record or fails after a timeout
+func receive(t *testing.T, records chan Record) Record {
+	select {
+	case record := <-records:
+		return record
+	case <-time.After(5 * time.Second):
+		t.Fatal("no message received")
+		return Record{}
+	}
+}
+
+func TestParse(t *testing.T) {
+	expect := ttesting.NewExpect(t)
+	server, err := NewServer(Config{Grok: grok.Config{NamedCapturesOnly: true}}, nil)
+	expect.NoError(err)
+
+	record := server.Parse([]byte("<165>1 2003-10-11T22:14:15.003Z mymachine.example.com evntslog - ID47 [exampleSDID@32473 iut=\"3\"] An application event\n"), "10.0.0.1:514")
+	expect.Equal("%{SYSLOG5424LINE}", record.Pattern)
+	expect.Equal(20, record.Facility)
+	expect.Equal(5, record.Severity)
+	expect.Equal("10.0.0.1:514", record.Remote)
+	expect.Equal("mymachine.example.com", record.Fields["syslog5424_host"])
+	expect.Equal("evntslog", record.Fields["syslog5424_app"])
+	expect.Equal("An application event", record.Fields["syslog5424_msg"])
+
+	record = server.Parse([]byte("<34>Oct 11 22:14:15 mymachine su[123]: 'su root' failed for lonvick on /dev/pts/8"), "")
+	expect.Equal(DefaultExpressions[1], record.Pattern)
+	expect.Equal(4, record.Facility)
+	expect.Equal(2, record.Severity)
+	expect.Equal("mymachine", record.Fields["logsource"])
+	expect.Equal("su", record.Fields["program"])
+	expect.Equal("123", record.Fields["pid"])
+	expect.Equal("'su root' failed for lonvick on /dev/pts/8", record.Fields["message"])
+
+	record = server.Parse([]byte("not syslog"), "")
+	expect.Equal("", record.Pattern)
+	expect.Equal(-1, record.Facility)
+	expect.Equal(0, len(record.Fields))
+}
+
+func TestUserPatterns(t *testing.T) {
+	expect := ttesting.NewExpect(t)
+	server, err := NewServer(Config{
+		Grok: grok.Config{
+			NamedCapturesOnly: true,
+			Patterns:          map[string]string{"LOGIN": `%{SYSLOGBASE} login of %{USERNAME:user}`},
+		},
+		Expressions: []string{"(?:%{SYSLOG5424PRI})?%{LOGIN}"},
+	}, nil)
+	expect.NoError(err)
+
+	record := server.Parse([]byte("<38>Oct 11 22:14:15 host sshd[7]: login of amy"), "")
+	expect.Equal("(?:%{SYSLOG5424PRI})?%{LOGIN}", record.Pattern)
+	expect.Equal("amy", record.Fields["user"])
+
+	// User patterns replace default patterns of the same name
+	server, err = NewServer(Config{
+		Grok: grok.Config{
+			NamedCapturesOnly: true,
+			Patterns:          map[string]string{"SYSLOGPROG": `%{PROG:app}(?:\[%{POSINT:pid}\])?`},
+		},
+	}, nil)
+	expect.NoError(err)
+	record = server.Parse([]byte("<38>Oct 11 22:14:15 host sshd[7]: login of amy"), "")
+	expect.Equal("sshd", record.Fields["app"])
+	_, hasProgram := record.Fields["program"]
+	expect.False(hasProgram)
+
+	_, err = NewServer(Config{Expressions: []string{"%{UNKNOWN}"}}, nil)
+	expect.NotNil(err)
+}
+
+func TestListenUDP(t *testing.T) {
+	expect := ttesting.NewExpect(t)
+	handler, records := collect()
+	server, err := NewServer(Config{Grok: grok.Config{NamedCapturesOnly: true}}, handler)
+	expect.NoError(err)
+	defer server.Close()
+
+	addr, err := server.ListenUDP("127.0.0.1:0")
+	expect.NoError(err)
+
+	conn, err := net.Dial("udp", addr.String())
+	expect.NoError(err)
+	defer conn.Close()
+	conn.Write([]byte("<13>Oct 11 22:14:15 host app: hello\n"))
+
+	record := receive(t, records)
+	expect.Equal("hello", record.Fields["message"])
+	expect.True(strings.HasPrefix(record.Remote, "127.0.0.1:"))
+}
+
+func TestListenTCP(t *testing.T) {
+	expect := ttesting.NewExpect(t)
+	handler, records := collect()
+	server, err := NewServer(Config{Grok: grok.Config{NamedCapturesOnly: true}, MaxMessageSize: 64}, handler)
+	expect.NoError(err)
+
+	addr, err := server.ListenTCP("127.0.0.1:0", nil)
+	expect.NoError(err)
+
+	conn, err := net.Dial("tcp", addr.String())
+	expect.NoError(err)
+	first := "<13>Oct 11 22:14:15 host app: one"
+	conn.Write([]byte(first + "\n<13>Oct 11 22:14:15 host app: two\r\n"))
+	counted := "<13>Oct 11 22:14:15 host app: three"
+	conn.Write([]byte(strconv.Itoa(len(counted)) + " " + counted))
+	conn.Write([]byte("<13>Oct 11 22:14:15 host app: " + strings.Repeat("x", 100) + "\n"))
+	conn.Write([]byte("100 too long"))
+
+	expect.Equal("one", receive(t, records).Fields["message"])
+	expect.Equal("two", receive(t, records).Fields["message"])
+	expect.Equal("three", receive(t, records).Fields["message"])
+	expect.Equal(64, len(receive(t, records).Message))
+
+	// The connection is closed after an oversized frame
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	_, err = conn.Read(make([]byte, 1))
+	expect.NotNil(err)
+	conn.Close()
+
+	expect.NoError(server.Close())
+	_, err = server.ListenTCP("127.0.0.1:0", nil)
+	expect.Equal(ErrServerClosed, err)
+}
+
+func TestListenUnix(t *testing.T) {
+	expect := ttesting.NewExpect(t)
+	handler, records := collect()
+	server, err := NewServer(Config{Grok: grok.Config{NamedCapturesOnly: true}}, handler)
+	expect.NoError(err)
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "log")
+	expect.NoError(server.ListenUnix("unixgram", path))
+	expect.NotNil(server.ListenUnix("tcp", path))
+
+	conn, err := net.Dial("unixgram", path)
+	expect.NoError(err)
+	defer conn.Close()
+	conn.Write([]byte("<13>Oct 11 22:14:15 host app: local"))
+
+	record := receive(t, records)
+	expect.Equal("local", record.Fields["message"])
+	expect.Equal("", record.Remote)
+}
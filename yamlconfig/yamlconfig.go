@@ -0,0 +1,66 @@
+// Package yamlconfig reads grok configuration files stored as YAML. It is
+// kept apart from package grok, so that only users of YAML configurations
+// depend on gopkg.in/yaml.v2.
+package yamlconfig
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/rtkjweeks/grok-go-pcre"
+	"gopkg.in/yaml.v2"
+)
+
+// Load reads a YAML configuration file and builds the parsers it describes,
+// like grok.LoadConfig does for JSON files.
+func Load(path string) (*grok.Parsers, error) {
+	return grok.LoadConfigWith(path, Read)
+}
+
+// Read reads a configuration stored as YAML, using the keys of the JSON
+// format, see grok.FileConfig. Like grok.ReadConfig, unknown keys are
+// rejected.
+func Read(r io.Reader) (*grok.FileConfig, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	var document interface{}
+	if err := yaml.Unmarshal(data, &document); err != nil {
+		return nil, err
+	}
+	if document == nil {
+		return &grok.FileConfig{}, nil
+	}
+
+	// The YAML document is converted to JSON, so both formats are decoded
+	// by the same rules
+	encoded, err := json.Marshal(toJSON(document))
+	if err != nil {
+		return nil, err
+	}
+	return grok.ReadConfig(bytes.NewReader(encoded))
+}
+
+// toJSON converts the maps of a decoded YAML document, which may have keys
+// of any type, to maps with string keys
+func toJSON(value interface{}) interface{} {
+	switch value := value.(type) {
+	case map[interface{}]interface{}:
+		converted := make(map[string]interface{}, len(value))
+		for key, item := range value {
+			converted[fmt.Sprint(key)] = toJSON(item)
+		}
+		return converted
+	case []interface{}:
+		converted := make([]interface{}, len(value))
+		for i, item := range value {
+			converted[i] = toJSON(item)
+		}
+		return converted
+	default:
+		return value
+	}
+}
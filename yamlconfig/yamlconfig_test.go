@@ -0,0 +1,76 @@
+package yamlconfig
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/trivago/tgo/ttesting"
+)
+
+const testConfig = `
+named_captures_only: true
+pattern_files: [patterns]
+patterns:
+  # replaces the default pattern
+  WORD: '[a-z]+'
+expressions:
+  app: '%{WORD:level} \[%{APPUSER:user}\] %{GREEDYDATA:message}'
+sources:
+  app:
+    expressions: [app]
+    redact:
+      - fields: [user]
+        action: mask
+        keep: 1
+`
+
+// writeConfig writes a configuration and a pattern file to a temporary
+// directory and returns the path of the configuration
+func writeConfig(t *testing.T, config string) string {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "patterns"), []byte("APPUSER [a-z]+\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "grok.yml")
+	if err := os.WriteFile(path, []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoad(t *testing.T) {
+	expect := ttesting.NewExpect(t)
+
+	parsers, err := Load(writeConfig(t, testConfig))
+	expect.NoError(err)
+
+	app := parsers.Source("app")
+	matched, fields, _, err := app.Parse("info [amy] started")
+	expect.NoError(err)
+	expect.True(matched)
+	expect.Equal(map[string]interface{}{"level": "info", "user": "**y", "message": "started"}, fields)
+
+	matched, _, _, err = app.Parse("INFO [amy] started")
+	expect.NoError(err)
+	expect.False(matched)
+}
+
+func TestRead(t *testing.T) {
+	expect := ttesting.NewExpect(t)
+
+	config, err := Read(strings.NewReader(""))
+	expect.NoError(err)
+	expect.Equal(0, len(config.Sources))
+
+	config, err = Read(strings.NewReader("patterns:\n  1: '[0-9]'\n"))
+	expect.NoError(err)
+	expect.Equal(map[string]string{"1": "[0-9]"}, config.Patterns)
+
+	// Unknown keys are rejected like in JSON files
+	_, err = Read(strings.NewReader("sources:\n  web:\n    expression: ['%{WORD}']\n"))
+	expect.NotNil(err)
+	_, err = Read(strings.NewReader("sources: [\n"))
+	expect.NotNil(err)
+}